@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminArgs struct {
+		nodes []string
+
+		txSeq        uint64
+		wait         bool
+		pollInterval time.Duration
+		timeout      time.Duration
+	}
+
+	adminCmd = &cobra.Command{
+		Use:   "admin",
+		Short: "Manage storage nodes via admin RPCs",
+	}
+
+	adminStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Query status of storage nodes",
+		Run:   adminStatus,
+	}
+
+	adminShutdownCmd = &cobra.Command{
+		Use:   "shutdown",
+		Short: "Shutdown storage nodes",
+		Run:   adminShutdown,
+	}
+
+	adminSyncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Sync file on storage nodes",
+	}
+
+	adminSyncStartCmd = &cobra.Command{
+		Use:   "start",
+		Short: "Start to sync file on storage nodes",
+		Run:   adminSyncStart,
+	}
+
+	adminSyncStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Query file sync status on storage nodes",
+		Run:   adminSyncStatus,
+	}
+)
+
+func init() {
+	adminCmd.PersistentFlags().StringSliceVar(&adminArgs.nodes, "node", []string{}, "Ionian storage node URLs separated by comma")
+	adminCmd.MarkPersistentFlagRequired("node")
+	addOutputFlag(adminCmd)
+
+	adminSyncCmd.PersistentFlags().Uint64Var(&adminArgs.txSeq, "tx-seq", 0, "Transaction sequence number of file to sync")
+	adminSyncCmd.MarkPersistentFlagRequired("tx-seq")
+	adminSyncCmd.PersistentFlags().BoolVar(&adminArgs.wait, "wait", false, "Whether to wait for file sync completed or failed")
+	adminSyncCmd.PersistentFlags().DurationVar(&adminArgs.pollInterval, "poll-interval", 3*time.Second, "Interval to poll file sync status if wait")
+	adminSyncCmd.PersistentFlags().DurationVar(&adminArgs.timeout, "timeout", 0, "Maximum time to wait for file sync finished if wait, 0 for no limit")
+
+	adminSyncCmd.AddCommand(adminSyncStartCmd, adminSyncStatusCmd)
+	adminCmd.AddCommand(adminStatusCmd, adminShutdownCmd, adminSyncCmd)
+	rootCmd.AddCommand(adminCmd)
+}
+
+// printAdminResults prints results, and exits with error if failed on any storage node.
+func printAdminResults(results []node.AdminResult, resultHeader string, succeeded func(result interface{}) bool) {
+	var rows [][]interface{}
+	var failures int
+
+	for _, v := range results {
+		if len(v.Error) > 0 || (succeeded != nil && !succeeded(v.Result)) {
+			failures++
+		}
+
+		result := "-"
+		if v.Result != nil {
+			result = fmt.Sprint(v.Result)
+		}
+
+		errMsg := "-"
+		if len(v.Error) > 0 {
+			errMsg = v.Error
+		}
+
+		rows = append(rows, []interface{}{v.Node, result, errMsg})
+	}
+
+	printOutput([]string{"NODE", resultHeader, "ERROR"}, rows, results)
+
+	if failures > 0 {
+		logrus.WithField("failures", failures).Fatal("Failed on some storage nodes")
+	}
+}
+
+func adminStatus(*cobra.Command, []string) {
+	clients := node.MustNewClients(adminArgs.nodes, nodeOption)
+
+	results := node.CallAdmin(clients, func(client *node.Client) (interface{}, error) {
+		status, err := client.GetStatus()
+		if err != nil {
+			return nil, err
+		}
+
+		return status.ConnectedPeers, nil
+	})
+
+	printAdminResults(results, "CONNECTED PEERS", nil)
+}
+
+func adminShutdown(*cobra.Command, []string) {
+	clients := node.MustNewClients(adminArgs.nodes, nodeOption)
+
+	results := node.CallAdmin(clients, func(client *node.Client) (interface{}, error) {
+		return client.Shutdown()
+	})
+
+	printAdminResults(results, "RESULT", nil)
+}
+
+func adminSyncStart(*cobra.Command, []string) {
+	clients := node.MustNewClients(adminArgs.nodes, nodeOption)
+
+	results := node.CallAdmin(clients, func(client *node.Client) (interface{}, error) {
+		return client.StartSyncFile(adminArgs.txSeq)
+	})
+
+	if !adminArgs.wait {
+		printAdminResults(results, "RESULT", nil)
+		return
+	}
+
+	// only wait for nodes that started to sync file successfully
+	var started []*node.Client
+	for i, v := range results {
+		if len(v.Error) == 0 {
+			started = append(started, clients[i])
+		} else {
+			logrus.WithField("node", v.Node).WithField("error", v.Error).Warn("Failed to start to sync file")
+		}
+	}
+
+	waitResults, err := node.WaitForSyncFinished(started, adminArgs.txSeq, adminArgs.pollInterval, adminArgs.timeout)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to wait for file sync finished")
+	}
+
+	// keep failures of nodes that failed to start to sync file
+	for i, j := 0, 0; i < len(results); i++ {
+		if len(results[i].Error) == 0 {
+			results[i] = waitResults[j]
+			j++
+		}
+	}
+
+	printAdminResults(results, "SYNC STATUS", isSyncCompleted)
+}
+
+func adminSyncStatus(*cobra.Command, []string) {
+	clients := node.MustNewClients(adminArgs.nodes, nodeOption)
+
+	if adminArgs.wait {
+		results, err := node.WaitForSyncFinished(clients, adminArgs.txSeq, adminArgs.pollInterval, adminArgs.timeout)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to wait for file sync finished")
+		}
+
+		printAdminResults(results, "SYNC STATUS", isSyncCompleted)
+		return
+	}
+
+	results := node.CallAdmin(clients, func(client *node.Client) (interface{}, error) {
+		return client.GetSyncStatus(adminArgs.txSeq)
+	})
+
+	printAdminResults(results, "SYNC STATUS", nil)
+}
+
+func isSyncCompleted(result interface{}) bool {
+	status, ok := result.(string)
+	return ok && node.IsSyncCompleted(status)
+}
@@ -12,6 +12,10 @@ var (
 		file  string
 		nodes []string
 		root  string
+		txSeq uint64
+
+		batchSize int
+		proof     bool
 	}
 
 	downloadCmd = &cobra.Command{
@@ -27,17 +31,37 @@ func init() {
 	downloadCmd.Flags().StringSliceVar(&downloadArgs.nodes, "node", []string{}, "Ionian storage node URL")
 	downloadCmd.MarkFlagRequired("node")
 	downloadCmd.Flags().StringVar(&downloadArgs.root, "root", "", "Merkle root to download file")
-	downloadCmd.MarkFlagRequired("root")
+	downloadCmd.Flags().Uint64Var(&downloadArgs.txSeq, "tx-seq", 0, "Transaction sequence number to download file, instead of merkle root")
+	downloadCmd.Flags().IntVar(&downloadArgs.batchSize, "batch-size", file.DefaultDownloadOption().BatchSize, "Number of segments to download in a JSON-RPC batch")
+	downloadCmd.Flags().BoolVar(&downloadArgs.proof, "proof", false, "Download segments with merkle proof and validate each segment against the file root")
 
 	rootCmd.AddCommand(downloadCmd)
 }
 
-func download(*cobra.Command, []string) {
-	nodes := node.MustNewClients(downloadArgs.nodes)
+func download(cmd *cobra.Command, _ []string) {
+	byTxSeq := cmd.Flags().Changed("tx-seq")
+	if len(downloadArgs.root) > 0 == byTxSeq {
+		logrus.Fatal("Either root or tx seq should be specified")
+	}
+
+	pool := node.MustNewPool(downloadArgs.nodes, nodeOption, node.DefaultPoolOption())
+	pool.Start()
+	defer pool.Close()
 
-	downloader := file.NewDownloader(nodes...)
+	downloader := file.NewDownloaderWithPool(pool, file.DownloadOption{
+		BatchSize: downloadArgs.batchSize,
+		Layout:    fileLayout(),
+		Proof:     downloadArgs.proof,
+	})
+
+	var err error
+	if byTxSeq {
+		err = downloader.DownloadByTxSeq(downloadArgs.txSeq, downloadArgs.file)
+	} else {
+		err = downloader.Download(downloadArgs.root, downloadArgs.file)
+	}
 
-	if err := downloader.Download(downloadArgs.root, downloadArgs.file); err != nil {
+	if err != nil {
 		logrus.WithError(err).Fatal("Failed to download file")
 	}
 }
@@ -8,7 +8,9 @@ import (
 
 var (
 	gatewayArgs struct {
-		nodes []string
+		nodes  []string
+		pool   node.PoolOption
+		config gateway.Config
 	}
 
 	gatewayCmd = &cobra.Command{
@@ -24,12 +26,35 @@ func init() {
 		"http://127.0.0.1:5679",
 		"http://127.0.0.1:5680",
 	}, "Storage node list separated by comma")
-	gatewayCmd.Flags().StringVar(&gateway.LocalFileRepo, "repo", "", "Local file repository")
+
+	defaultPoolOption := node.DefaultPoolOption()
+	gatewayCmd.Flags().DurationVar(&gatewayArgs.pool.HealthCheckInterval, "health-check-interval", defaultPoolOption.HealthCheckInterval, "Interval to check health of storage nodes")
+	gatewayCmd.Flags().DurationVar(&gatewayArgs.pool.HealthCheckTimeout, "health-check-timeout", defaultPoolOption.HealthCheckTimeout, "Timeout to check health of a storage node")
+	gatewayCmd.Flags().Float64Var(&gatewayArgs.pool.MaxErrorRate, "max-error-rate", defaultPoolOption.MaxErrorRate, "Storage node will be evicted if RPC error rate exceeds")
+
+	config := &gatewayArgs.config
+	defaults := gateway.DefaultConfig()
+
+	gatewayCmd.Flags().StringVar(&config.Endpoint, "endpoint", defaults.Endpoint, "Address to listen on")
+	gatewayCmd.Flags().StringVar(&config.TLSCertFile, "tls-cert", "", "TLS certificate file to serve HTTPS")
+	gatewayCmd.Flags().StringVar(&config.TLSKeyFile, "tls-key", "", "TLS private key file to serve HTTPS")
+	gatewayCmd.Flags().StringSliceVar(&config.AuthTokens, "auth-tokens", []string{}, "Bearer tokens or API keys separated by comma to authenticate requests, disabled if not specified")
+	gatewayCmd.Flags().Int64Var(&config.MaxRequestBodySize, "max-body-size", defaults.MaxRequestBodySize, "Maximum request body size in bytes, 0 for unlimited")
+	gatewayCmd.Flags().DurationVar(&config.ShutdownTimeout, "shutdown-timeout", defaults.ShutdownTimeout, "Maximum time to drain in-flight jobs when shutdown")
+
+	gatewayCmd.Flags().StringVar(&config.LocalFileRepo, "repo", defaults.LocalFileRepo, "Local file repository")
+	gatewayCmd.Flags().BoolVar(&config.AllowAbsolutePath, "allow-abs-path", false, "Whether to allow absolute file path out of local file repository")
+	gatewayCmd.Flags().StringSliceVar(&config.AllowedOrigins, "allow-origins", []string{}, "Origins allowed for cross-domain requests separated by comma, or * to allow all. Cross-domain requests are denied if not specified")
 
 	rootCmd.AddCommand(gatewayCmd)
 }
 
 func startGateway(*cobra.Command, []string) {
-	nodes := node.MustNewClients(gatewayArgs.nodes)
-	gateway.MustServeLocal(nodes)
+	pool := node.MustNewPool(gatewayArgs.nodes, nodeOption, gatewayArgs.pool)
+	pool.Start()
+	defer pool.Close()
+
+	gatewayArgs.config.MerkleCacheDir = merkleCacheDir
+
+	gateway.MustServe(gatewayArgs.config, pool)
 }
@@ -66,7 +66,7 @@ func generateTempFile(*cobra.Command, []string) {
 		logrus.WithError(err).Fatal("Failed to write file")
 	}
 
-	file, err := file.Open(genFileArgs.file)
+	file, err := file.Open(genFileArgs.file, fileLayout())
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to open file")
 	}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var outputFormat string
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, "Output format, table or json")
+}
+
+// printOutput prints rows as a table with header, or prints the JSON value, according to the output flag.
+func printOutput(header []string, rows [][]interface{}, jsonValue interface{}) {
+	switch outputFormat {
+	case outputJSON:
+		encoded, err := json.MarshalIndent(jsonValue, "", "  ")
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to marshal output in JSON")
+		}
+
+		fmt.Println(string(encoded))
+	case outputTable:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+		for _, row := range rows {
+			var cells []string
+			for _, cell := range row {
+				cells = append(cells, fmt.Sprint(cell))
+			}
+
+			fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		}
+
+		writer.Flush()
+	default:
+		logrus.WithField("output", outputFormat).Fatal("Invalid output format")
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proofArgs struct {
+		file    string
+		chunk   uint32
+		segment uint32
+
+		root    string
+		size    int64
+		proof   string
+		content string
+	}
+
+	proofCmd = &cobra.Command{
+		Use:   "proof",
+		Short: "Generate or verify merkle proof of file content",
+	}
+
+	proofGenCmd = &cobra.Command{
+		Use:   "gen",
+		Short: "Generate merkle proof of chunk or segment in file",
+		Run:   proofGen,
+	}
+
+	proofVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify merkle proof of chunk or segment with file merkle root",
+		Run:   proofVerify,
+	}
+)
+
+func init() {
+	proofCmd.PersistentFlags().StringVar(&proofArgs.proof, "proof", "", "Proof file in JSON format")
+	proofCmd.MarkPersistentFlagRequired("proof")
+	proofCmd.PersistentFlags().StringVar(&proofArgs.content, "content", "", "Content file of chunk or segment")
+	proofCmd.MarkPersistentFlagRequired("content")
+
+	proofGenCmd.Flags().StringVar(&proofArgs.file, "file", "", "File to generate proof")
+	proofGenCmd.MarkFlagRequired("file")
+	proofGenCmd.Flags().Uint32Var(&proofArgs.chunk, "chunk", 0, "Chunk index to generate proof")
+	proofGenCmd.Flags().Uint32Var(&proofArgs.segment, "segment", 0, "Segment index to generate proof")
+
+	proofVerifyCmd.Flags().StringVar(&proofArgs.root, "root", "", "File merkle root to verify proof")
+	proofVerifyCmd.MarkFlagRequired("root")
+	proofVerifyCmd.Flags().Int64Var(&proofArgs.size, "size", 0, "File size in bytes to verify proof position")
+	proofVerifyCmd.MarkFlagRequired("size")
+	proofVerifyCmd.Flags().Uint32Var(&proofArgs.chunk, "chunk", 0, "Chunk index to verify proof")
+	proofVerifyCmd.Flags().Uint32Var(&proofArgs.segment, "segment", 0, "Segment index to verify proof")
+
+	proofCmd.AddCommand(proofGenCmd, proofVerifyCmd)
+	rootCmd.AddCommand(proofCmd)
+}
+
+// proofPosition returns the proof type and index of chunk or segment specified in flags.
+func proofPosition(cmd *cobra.Command) (string, uint32) {
+	byChunk, bySegment := cmd.Flags().Changed("chunk"), cmd.Flags().Changed("segment")
+	if byChunk == bySegment {
+		logrus.Fatal("Either chunk or segment should be specified")
+	}
+
+	if bySegment {
+		return file.ProofTypeSegment, proofArgs.segment
+	}
+
+	return file.ProofTypeChunk, proofArgs.chunk
+}
+
+func proofGen(cmd *cobra.Command, _ []string) {
+	proofType, index := proofPosition(cmd)
+
+	f, err := file.Open(proofArgs.file, fileLayout())
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open file")
+	}
+	defer f.Close()
+
+	tree, err := f.MerkleTree()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create file merkle tree")
+	}
+
+	proof, content, err := f.ContentProof(tree, proofType, index)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to generate proof")
+	}
+
+	encoded, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal proof")
+	}
+
+	if err = os.WriteFile(proofArgs.proof, encoded, 0644); err != nil {
+		logrus.WithError(err).Fatal("Failed to write proof file")
+	}
+
+	if err = os.WriteFile(proofArgs.content, content, 0644); err != nil {
+		logrus.WithError(err).Fatal("Failed to write content file")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"root":  tree.Root(),
+		"type":  proofType,
+		"index": index,
+	}).Info("Proof generated")
+}
+
+func proofVerify(cmd *cobra.Command, _ []string) {
+	proofType, index := proofPosition(cmd)
+
+	encoded, err := os.ReadFile(proofArgs.proof)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read proof file")
+	}
+
+	var proof file.ContentProof
+	if err = json.Unmarshal(encoded, &proof); err != nil {
+		logrus.WithError(err).Fatal("Failed to unmarshal proof")
+	}
+
+	content, err := os.ReadFile(proofArgs.content)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read content file")
+	}
+
+	if err = proof.ValidatePosition(proofType, index, proofArgs.size); err != nil {
+		logrus.WithError(err).Fatal("Failed to verify proof position")
+	}
+
+	if err = proof.Validate(common.HexToHash(proofArgs.root), content); err != nil {
+		logrus.WithError(err).Fatal("Failed to verify proof")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"type":     proof.Type,
+		"position": proof.Position,
+	}).Info("Proof verified")
+}
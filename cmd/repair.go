@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repairArgs struct {
+		nodes []string
+
+		roots      []string
+		txSeqStart uint64
+		txSeqEnd   uint64
+
+		pollInterval time.Duration
+		timeout      time.Duration
+	}
+
+	repairCmd = &cobra.Command{
+		Use:   "repair",
+		Short: "Re-sync files that missed or not finalized on storage nodes",
+		Run:   repair,
+	}
+)
+
+func init() {
+	repairCmd.Flags().StringSliceVar(&repairArgs.nodes, "node", []string{}, "Ionian storage node URLs separated by comma")
+	repairCmd.MarkFlagRequired("node")
+
+	repairCmd.Flags().StringSliceVar(&repairArgs.roots, "roots", []string{}, "Merkle roots of files to repair separated by comma")
+	repairCmd.Flags().Uint64Var(&repairArgs.txSeqStart, "tx-seq-start", 0, "Start transaction sequence number of files to repair (inclusive)")
+	repairCmd.Flags().Uint64Var(&repairArgs.txSeqEnd, "tx-seq-end", 0, "End transaction sequence number of files to repair (inclusive)")
+
+	repairCmd.Flags().DurationVar(&repairArgs.pollInterval, "poll-interval", 3*time.Second, "Interval to poll file sync status")
+	repairCmd.Flags().DurationVar(&repairArgs.timeout, "timeout", 0, "Maximum time to wait for all file sync finished, 0 for no limit")
+	addOutputFlag(repairCmd)
+
+	rootCmd.AddCommand(repairCmd)
+}
+
+func repair(cmd *cobra.Command, _ []string) {
+	byTxSeq := cmd.Flags().Changed("tx-seq-start") || cmd.Flags().Changed("tx-seq-end")
+
+	if len(repairArgs.roots) > 0 == byTxSeq {
+		logrus.Fatal("Either roots or tx seq range should be specified")
+	}
+
+	if byTxSeq && repairArgs.txSeqStart > repairArgs.txSeqEnd {
+		logrus.WithFields(logrus.Fields{
+			"start": repairArgs.txSeqStart,
+			"end":   repairArgs.txSeqEnd,
+		}).Fatal("Invalid tx seq range")
+	}
+
+	clients := node.MustNewClients(repairArgs.nodes, nodeOption)
+	repairer, err := node.NewRepairer(clients, node.RepairOption{
+		PollInterval: repairArgs.pollInterval,
+		Timeout:      repairArgs.timeout,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create repairer")
+	}
+
+	var items []*node.RepairItem
+	if byTxSeq {
+		items = repairer.RepairByTxSeqs(repairArgs.txSeqStart, repairArgs.txSeqEnd)
+	} else {
+		var roots []common.Hash
+		for _, v := range repairArgs.roots {
+			roots = append(roots, common.HexToHash(v))
+		}
+
+		items = repairer.RepairByRoots(roots)
+	}
+
+	var rows [][]interface{}
+	var failures int
+
+	for _, v := range items {
+		if !v.Repaired() {
+			failures++
+		}
+
+		rows = append(rows, []interface{}{
+			valueOrDash(v.TxSeq), valueOrDash(v.Root), v.Node, v.State, valueOrDash(v.SyncStatus), valueOrDash(v.Error),
+		})
+	}
+
+	printOutput([]string{"TX SEQ", "ROOT", "NODE", "STATE", "SYNC STATUS", "ERROR"}, rows, items)
+
+	if failures > 0 {
+		logrus.WithFields(logrus.Fields{
+			"failures": failures,
+			"total":    len(items),
+		}).Fatal("Failed to repair some files")
+	}
+
+	logrus.WithField("total", len(items)).Info("All files repaired")
+}
+
+// valueOrDash returns "-" for nil pointer or empty string, so as to output in table.
+func valueOrDash(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if len(v) == 0 {
+			return "-"
+		}
+	case *uint64:
+		if v == nil {
+			return "-"
+		}
+
+		return *v
+	case *common.Hash:
+		if v == nil {
+			return "-"
+		}
+
+		return v.Hex()
+	}
+
+	return value
+}
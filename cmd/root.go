@@ -4,20 +4,27 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
 	"github.com/Ionian-Web3-Storage/ionian-client/contract"
+	"github.com/Ionian-Web3-Storage/ionian-client/file"
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logLevel       string
-	logColorForced bool
+	logLevel        string
+	logColorForced  bool
+	metricsEndpoint string
+	nodeOption      node.ClientOption
+	merkleCacheDir  string
 
 	rootCmd = &cobra.Command{
 		Use:   "ionian-client",
 		Short: "Ionian client to interact with Ionian network",
 		PersistentPreRun: func(*cobra.Command, []string) {
 			initLog()
+			initMetrics()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
@@ -28,10 +35,27 @@ var (
 func init() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logrus.InfoLevel.String(), "Log level")
 	rootCmd.PersistentFlags().BoolVar(&logColorForced, "log-force-color", false, "Force to output colorful logs")
+	rootCmd.PersistentFlags().StringVar(&metricsEndpoint, "metrics-endpoint", "", "Address to serve Prometheus metrics on /metrics, e.g. 127.0.0.1:6060, disabled if not specified")
+
+	defaultNodeOption := node.DefaultClientOption()
+	rootCmd.PersistentFlags().DurationVar(&nodeOption.Timeout, "rpc-timeout", defaultNodeOption.Timeout, "Timeout for each RPC call to storage node, 0 for no timeout")
+	rootCmd.PersistentFlags().IntVar(&nodeOption.MaxRetries, "rpc-retry", defaultNodeOption.MaxRetries, "Maximum retries for idempotent RPC calls to storage node")
+	rootCmd.PersistentFlags().DurationVar(&nodeOption.RetryInterval, "rpc-retry-interval", defaultNodeOption.RetryInterval, "Initial backoff to retry RPC calls, doubled for subsequent retries")
+	rootCmd.PersistentFlags().DurationVar(&nodeOption.MaxRetryInterval, "rpc-retry-max-interval", defaultNodeOption.MaxRetryInterval, "Maximum backoff to retry RPC calls")
+	rootCmd.PersistentFlags().Float64Var(&nodeOption.RateLimit, "rpc-rate-limit", defaultNodeOption.RateLimit, "Maximum RPC calls per second to each storage node, 0 for unlimited")
+	rootCmd.PersistentFlags().IntVar(&nodeOption.RateBurst, "rpc-rate-burst", defaultNodeOption.RateBurst, "Maximum burst RPC calls to each storage node")
+
+	rootCmd.PersistentFlags().StringVar(&merkleCacheDir, "merkle-cache-dir", "", "Directory to cache merkle tree of local files, disabled if not specified")
+
 	rootCmd.PersistentFlags().Uint64Var(&contract.CustomGasPrice, "gas-price", 0, "Custom gas price to send transaction")
 	rootCmd.PersistentFlags().Uint64Var(&contract.CustomGasLimit, "gas-limit", 0, "Custom gas limit to send transaction")
 }
 
+// fileLayout returns the default file layout with merkle tree cache dir specified in flags.
+func fileLayout() file.Layout {
+	return file.Layout{MerkleCacheDir: merkleCacheDir}
+}
+
 func initLog() {
 	if logColorForced {
 		logrus.SetFormatter(&logrus.TextFormatter{
@@ -48,6 +72,12 @@ func initLog() {
 	logrus.SetLevel(level)
 }
 
+func initMetrics() {
+	if len(metricsEndpoint) > 0 {
+		metrics.MustServe(metricsEndpoint)
+	}
+}
+
 // Execute is the command line entrypoint.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
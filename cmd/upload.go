@@ -18,7 +18,9 @@ var (
 		contract string
 		key      string
 
-		node string
+		node      string
+		batchSize int
+		routines  int
 	}
 
 	uploadCmd = &cobra.Command{
@@ -41,6 +43,8 @@ func init() {
 
 	uploadCmd.Flags().StringVar(&uploadArgs.node, "node", "", "Ionian storage node URL")
 	uploadCmd.MarkFlagRequired("node")
+	uploadCmd.Flags().IntVar(&uploadArgs.batchSize, "batch-size", file.DefaultUploadOption().BatchSize, "Number of segments to upload in a JSON-RPC batch")
+	uploadCmd.Flags().IntVar(&uploadArgs.routines, "routines", file.DefaultUploadOption().Routines, "Number of concurrent upload RPCs to storage node")
 
 	rootCmd.AddCommand(uploadCmd)
 }
@@ -51,10 +55,14 @@ func upload(*cobra.Command, []string) {
 	contractAddr := ethCommon.HexToAddress(uploadArgs.contract)
 	ionian := contract.MustNewFlow(contractAddr, client)
 
-	node := node.MustNewClient(uploadArgs.node)
+	node := node.MustNewClient(uploadArgs.node, nodeOption)
 	defer node.Close()
 
-	uploader := file.NewUploader(ionian, node)
+	uploader := file.NewUploader(ionian, node, file.UploadOption{
+		BatchSize: uploadArgs.batchSize,
+		Routines:  uploadArgs.routines,
+		Layout:    fileLayout(),
+	})
 
 	if err := uploader.Upload(uploadArgs.file); err != nil {
 		logrus.WithError(err).Fatal("Failed to upload file")
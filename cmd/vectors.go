@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/vectors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vectorsArgs struct {
+		dir string
+	}
+
+	vectorsCmd = &cobra.Command{
+		Use:   "vectors",
+		Short: "Generate test vectors of file roots, proofs and submissions in the format of regression snapshot",
+		Run:   generateVectors,
+	}
+)
+
+func init() {
+	vectorsCmd.Flags().StringVar(&vectorsArgs.dir, "dir", ".", "Directory to write file.json and merkle.json")
+
+	rootCmd.AddCommand(vectorsCmd)
+}
+
+func generateVectors(*cobra.Command, []string) {
+	if err := os.MkdirAll(vectorsArgs.dir, 0755); err != nil {
+		logrus.WithError(err).Fatal("Failed to create vectors dir")
+	}
+
+	tmpDir, err := ioutil.TempDir("", "ionian-vectors")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fileVectors, err := vectors.GenerateFiles(tmpDir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to generate file vectors")
+	}
+
+	merkleVectors, err := vectors.GenerateMerkle()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to generate merkle vectors")
+	}
+
+	for name, v := range map[string]interface{}{"file.json": fileVectors, "merkle.json": merkleVectors} {
+		filename := filepath.Join(vectorsArgs.dir, name)
+		if err = vectors.Write(filename, v); err != nil {
+			logrus.WithError(err).WithField("file", filename).Fatal("Failed to write vectors")
+		}
+	}
+
+	logrus.WithField("dir", vectorsArgs.dir).Info("Test vectors generated")
+}
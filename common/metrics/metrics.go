@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "ionian_client"
+
+var registry = prometheus.NewRegistry()
+
+// Upload & download
+var (
+	UploadBytes      = newCounterVec("upload_bytes_total", "Bytes uploaded to storage node", "node")
+	UploadSegments   = newCounterVec("upload_segments_total", "Segments uploaded to storage node", "node")
+	DownloadBytes    = newCounterVec("download_bytes_total", "Bytes downloaded from storage node", "node")
+	DownloadSegments = newCounterVec("download_segments_total", "Segments downloaded from storage node", "node")
+)
+
+// Storage node RPC
+var (
+	RPCDuration = newHistogramVec("rpc_duration_seconds", "Latency of storage node RPC", prometheus.DefBuckets, "node", "method")
+	RPCErrors   = newCounterVec("rpc_errors_total", "Errors of storage node RPC", "node", "method")
+)
+
+// Merkle tree
+var (
+	MerkleHashedBytes  = newCounter("merkle_hashed_bytes_total", "Bytes hashed to build file merkle tree")
+	MerkleHashDuration = newHistogram("merkle_hash_duration_seconds", "Time spent to build file merkle tree", prometheus.ExponentialBuckets(0.01, 4, 10))
+	MerkleCacheHits    = newCounter("merkle_cache_hits_total", "File merkle tree loaded from cache")
+	MerkleCacheMisses  = newCounter("merkle_cache_misses_total", "File merkle tree not cached or invalidated")
+)
+
+// Blockchain transaction
+var (
+	TxSubmitDuration = newHistogram("tx_submit_duration_seconds", "Latency from transaction submitted to executed", prometheus.ExponentialBuckets(1, 2, 8))
+	TxGasUsed        = newHistogram("tx_gas_used", "Gas used of executed transaction", prometheus.ExponentialBuckets(50000, 2, 8))
+)
+
+// Gateway
+var (
+	GatewayActiveJobs = newGauge("gateway_active_jobs", "Number of in-flight upload or download jobs in gateway")
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+func newCounter(name, help string) prometheus.Counter {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help})
+	registry.MustRegister(counter)
+	return counter
+}
+
+func newCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	registry.MustRegister(counter)
+	return counter
+}
+
+func newGauge(name, help string) prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help})
+	registry.MustRegister(gauge)
+	return gauge
+}
+
+func newHistogram(name, help string, buckets []float64) prometheus.Histogram {
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets})
+	registry.MustRegister(histogram)
+	return histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	registry.MustRegister(histogram)
+	return histogram
+}
+
+// Since returns the elapsed seconds since the specified time.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Handler returns the HTTP handler to expose all metrics in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// MustServe serves metrics on "/metrics" of the specified endpoint in background.
+func MustServe(endpoint string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	server := http.Server{
+		Addr:    endpoint,
+		Handler: mux,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			logrus.WithError(err).WithField("endpoint", endpoint).Fatal("Failed to serve metrics")
+		}
+	}()
+
+	logrus.WithField("endpoint", endpoint).Info("Metrics server started")
+}
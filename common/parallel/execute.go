@@ -0,0 +1,190 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Unordered executes tasks in parallel like Serial, but collects results in completion order
+// instead of task order. At most window tasks are dispatched but not collected yet, so as to
+// bound the memory of task results.
+func Unordered(parallelizable Interface, tasks, routines, window int) error {
+	return Execute(parallelizable, tasks, Option{
+		Routines: routines,
+		Window:   window,
+	})
+}
+
+// Execute executes tasks in parallel with the specified option. It terminates once any task
+// failed without retry, ParallelCollect failed or context cancelled, and all routines are joined
+// before return.
+func Execute(parallelizable Interface, tasks int, option Option) error {
+	if tasks <= 0 {
+		return nil
+	}
+
+	option.normalize(tasks)
+
+	ctx, cancel := context.WithCancel(option.Context)
+	defer cancel()
+
+	e := executor{
+		parallelizable: parallelizable,
+		option:         &option,
+		tasks:          tasks,
+		// channels are never closed, so that no routine sends on or receives from closed channel
+		taskCh:   make(chan int, option.Window),
+		resultCh: make(chan *Result, option.Window),
+	}
+
+	var wg sync.WaitGroup
+
+	// start routines to do tasks
+	for i := 0; i < option.Routines; i++ {
+		wg.Add(1)
+		go func(routine int) {
+			defer wg.Done()
+			e.work(ctx, routine)
+		}(i)
+	}
+
+	err := e.collect(ctx)
+
+	// notify all routines to terminate
+	cancel()
+
+	// wait for termination for all routines
+	wg.Wait()
+
+	return err
+}
+
+type executor struct {
+	parallelizable Interface
+	option         *Option
+	tasks          int
+
+	taskCh   chan int
+	resultCh chan *Result
+
+	// only accessed in collector routine
+	dispatched int
+	collected  int
+}
+
+func (e *executor) work(ctx context.Context, routine int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task := <-e.taskCh:
+			result, ok := e.do(ctx, routine, task)
+			if !ok {
+				return
+			}
+
+			// collector may terminate due to error
+			select {
+			case <-ctx.Done():
+				return
+			case e.resultCh <- result:
+			}
+		}
+	}
+}
+
+// do does the task and retries on failure if necessary. Returns false if context cancelled
+// during backoff.
+func (e *executor) do(ctx context.Context, routine, task int) (*Result, bool) {
+	for attempts := 1; ; attempts++ {
+		val, err := e.parallelizable.ParallelDo(routine, task)
+		if err == nil || e.option.Retry == nil {
+			return &Result{routine, task, val, err}, true
+		}
+
+		retry, backoff := e.option.Retry.ShouldRetry(task, attempts, err)
+		if !retry {
+			return &Result{routine, task, val, err}, true
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, false
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *executor) collect(ctx context.Context) error {
+	// fill window at first
+	for e.dispatched < e.option.Window && e.dispatched < e.tasks {
+		e.dispatch()
+	}
+
+	cache := map[int]*Result{}
+
+	for e.collected < e.tasks {
+		var result *Result
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result = <-e.resultCh:
+		}
+
+		if result.err != nil {
+			return result.err
+		}
+
+		if !e.option.Ordered {
+			if err := e.collectResult(result); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// handle task in sequence
+		cache[result.Task] = result
+
+		for cache[e.collected] != nil {
+			next := cache[e.collected]
+			delete(cache, next.Task)
+
+			if err := e.collectResult(next); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *executor) collectResult(result *Result) error {
+	if err := e.parallelizable.ParallelCollect(result); err != nil {
+		return err
+	}
+
+	e.collected++
+
+	if e.option.Progress != nil {
+		e.option.Progress(e.collected, e.tasks)
+	}
+
+	// dispatch new task once any task collected, so that at most window tasks in progress
+	if e.dispatched < e.tasks {
+		e.dispatch()
+	}
+
+	return nil
+}
+
+// dispatch never blocks, since at most window tasks dispatched but not collected yet.
+func (e *executor) dispatch() {
+	e.taskCh <- e.dispatched
+	e.dispatched++
+}
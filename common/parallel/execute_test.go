@@ -0,0 +1,232 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTaskFailed = errors.New("task failed")
+
+// testTasks squares task index, and fails some tasks for specified times.
+type testTasks struct {
+	mu        sync.Mutex
+	failures  map[int]int // task -> remaining failures, negative to always fail
+	attempts  map[int]int
+	collected []int
+	delay     func(task int) time.Duration
+}
+
+func newTestTasks(failures map[int]int) *testTasks {
+	if failures == nil {
+		failures = make(map[int]int)
+	}
+
+	return &testTasks{
+		failures: failures,
+		attempts: make(map[int]int),
+	}
+}
+
+func (tt *testTasks) ParallelDo(routine, task int) (interface{}, error) {
+	if tt.delay != nil {
+		time.Sleep(tt.delay(task))
+	}
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	tt.attempts[task]++
+
+	if remaining, ok := tt.failures[task]; ok && remaining != 0 {
+		tt.failures[task] = remaining - 1
+		return nil, errTaskFailed
+	}
+
+	return task * task, nil
+}
+
+func (tt *testTasks) ParallelCollect(result *Result) error {
+	if result.Value.(int) != result.Task*result.Task {
+		return errors.New("unexpected result")
+	}
+
+	tt.collected = append(tt.collected, result.Task)
+
+	return nil
+}
+
+// waitGoroutines waits for the number of goroutines back to the specified value.
+func waitGoroutines(t *testing.T, expected int) {
+	for i := 0; i < 100 && runtime.NumGoroutine() > expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), expected, "goroutines leaked")
+}
+
+func TestExecuteOrdered(t *testing.T) {
+	tt := newTestTasks(nil)
+	tt.delay = func(task int) time.Duration { return time.Duration(task%3) * time.Millisecond }
+
+	var progress []int
+	err := Execute(tt, 50, Option{
+		Routines: 4,
+		Window:   8,
+		Ordered:  true,
+		Progress: func(collected, total int) {
+			assert.Equal(t, 50, total)
+			progress = append(progress, collected)
+		},
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, i, tt.collected[i])
+		assert.Equal(t, i+1, progress[i])
+	}
+}
+
+func TestExecuteProgress(t *testing.T) {
+	tt := newTestTasks(nil)
+	tt.delay = func(task int) time.Duration { return time.Duration(task%3) * time.Millisecond }
+
+	var progress []int
+	err := Execute(tt, 50, Option{
+		Routines: 4,
+		Window:   8,
+		Progress: func(collected, total int) {
+			assert.Equal(t, 50, total)
+			progress = append(progress, collected)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, len(tt.collected))
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, i+1, progress[i])
+	}
+}
+
+func TestExecuteRetry(t *testing.T) {
+	tt := newTestTasks(map[int]int{3: 2, 10: 1})
+
+	err := Execute(tt, 20, Option{
+		Routines: 4,
+		Retry:    &ExponentialBackoff{MaxRetries: 2, Interval: time.Millisecond},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, len(tt.collected))
+	assert.Equal(t, 3, tt.attempts[3])
+	assert.Equal(t, 2, tt.attempts[10])
+	assert.Equal(t, 1, tt.attempts[0])
+
+	// retries exhausted
+	tt = newTestTasks(map[int]int{5: 3})
+	err = Execute(tt, 20, Option{
+		Routines: 4,
+		Retry:    &ExponentialBackoff{MaxRetries: 2, Interval: time.Millisecond},
+	})
+	assert.Equal(t, errTaskFailed, err)
+	assert.Equal(t, 3, tt.attempts[5])
+
+	// non-retryable error
+	tt = newTestTasks(map[int]int{5: 1})
+	err = Execute(tt, 20, Option{
+		Routines: 4,
+		Retry: &ExponentialBackoff{
+			MaxRetries: 2,
+			Retryable:  func(err error) bool { return err != errTaskFailed },
+		},
+	})
+	assert.Equal(t, errTaskFailed, err)
+	assert.Equal(t, 1, tt.attempts[5])
+}
+
+func TestExecuteCancel(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	tt := newTestTasks(map[int]int{0: -1})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// cancel during retry backoff
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	err := Execute(tt, 100, Option{
+		Context:  ctx,
+		Routines: 4,
+		Retry: RetryFunc(func(task, attempts int, err error) (bool, time.Duration) {
+			return true, time.Hour
+		}),
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	waitGoroutines(t, goroutines)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := ExponentialBackoff{MaxRetries: 5, Interval: time.Second, MaxInterval: 5 * time.Second}
+
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		retry, backoff := policy.ShouldRetry(0, i+1, errTaskFailed)
+		assert.True(t, retry)
+		assert.Equal(t, expected, backoff)
+	}
+
+	retry, _ := policy.ShouldRetry(0, 6, errTaskFailed)
+	assert.False(t, retry)
+}
+
+// TestExecuteStress executes tasks with random options and failures, and checks that execution
+// always terminates without goroutine leak.
+func TestExecuteStress(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for i := 0; i < 300; i++ {
+		tasks := 1 + r.Intn(100)
+		failures := make(map[int]int)
+		var expectErr bool
+
+		// inject permanent failures
+		if r.Intn(2) == 0 {
+			failures[r.Intn(tasks)] = -1
+			expectErr = true
+		}
+
+		// inject transient failures
+		for j := r.Intn(5); j > 0; j-- {
+			if task := r.Intn(tasks); failures[task] == 0 {
+				failures[task] = 1
+			}
+		}
+
+		tt := newTestTasks(failures)
+		if r.Intn(2) == 0 {
+			// complete tasks out of order
+			tt.delay = func(task int) time.Duration { return time.Duration(task%2) * 100 * time.Microsecond }
+		}
+
+		err := Execute(tt, tasks, Option{
+			Routines: 1 + r.Intn(8),
+			Window:   r.Intn(16),
+			Ordered:  r.Intn(2) == 0,
+			Retry:    &ExponentialBackoff{MaxRetries: 2, Interval: time.Microsecond},
+		})
+
+		if expectErr {
+			assert.Equal(t, errTaskFailed, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tasks, len(tt.collected))
+		}
+	}
+
+	waitGoroutines(t, goroutines)
+}
@@ -0,0 +1,84 @@
+package parallel
+
+import (
+	"context"
+	"time"
+)
+
+// Option is the option to execute tasks in parallel.
+type Option struct {
+	Context  context.Context // context to cancel execution, background context if nil
+	Routines int             // number of routines to do tasks, at least 1
+	Window   int             // maximum number of tasks dispatched but not collected yet, at least Routines
+	Ordered  bool            // whether to collect results in task order or completion order
+
+	// Retry decides whether to retry a failed task, nil to never retry. Note, ParallelDo will be
+	// called again for the same task if retried, so it should be safe to do so.
+	Retry RetryPolicy
+
+	// Progress is called in the collector routine once a task collected, nil to ignore.
+	Progress func(collected, total int)
+}
+
+func (option *Option) normalize(tasks int) {
+	if option.Context == nil {
+		option.Context = context.Background()
+	}
+
+	if option.Routines <= 0 {
+		option.Routines = 1
+	}
+
+	if option.Routines > tasks {
+		option.Routines = tasks
+	}
+
+	if option.Window < option.Routines {
+		option.Window = option.Routines
+	}
+}
+
+// RetryPolicy decides whether to retry a failed task.
+type RetryPolicy interface {
+	// ShouldRetry returns whether to retry the task that failed for attempts times with the
+	// specified error, and the backoff before next attempt.
+	ShouldRetry(task, attempts int, err error) (bool, time.Duration)
+}
+
+// RetryFunc is an adapter to allow the use of ordinary function as RetryPolicy.
+type RetryFunc func(task, attempts int, err error) (bool, time.Duration)
+
+// ShouldRetry implements the RetryPolicy interface.
+func (f RetryFunc) ShouldRetry(task, attempts int, err error) (bool, time.Duration) {
+	return f(task, attempts, err)
+}
+
+// ExponentialBackoff retries failed task with exponential backoff.
+type ExponentialBackoff struct {
+	MaxRetries  int
+	Interval    time.Duration    // backoff before the first retry, doubled for subsequent retries
+	MaxInterval time.Duration    // upper bound of backoff, 0 for unlimited
+	Retryable   func(error) bool // whether the error is retryable, nil to retry all errors
+}
+
+// ShouldRetry implements the RetryPolicy interface.
+func (policy *ExponentialBackoff) ShouldRetry(task, attempts int, err error) (bool, time.Duration) {
+	if attempts > policy.MaxRetries {
+		return false, 0
+	}
+
+	if policy.Retryable != nil && !policy.Retryable(err) {
+		return false, 0
+	}
+
+	interval := policy.Interval
+	for i := 1; i < attempts; i++ {
+		interval *= 2
+
+		if policy.MaxInterval > 0 && interval >= policy.MaxInterval {
+			return true, policy.MaxInterval
+		}
+	}
+
+	return true, interval
+}
@@ -1,7 +1,10 @@
 package parallel
 
 import (
+	"math/rand"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -38,3 +41,62 @@ func TestSerial(t *testing.T) {
 		assert.Equal(t, i*i, f.result[i])
 	}
 }
+
+// TestSerialError injects error at random task index, and checks that no task executed more than
+// once, results collected in order before the failed task, and all goroutines terminated.
+func TestSerialError(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for i := 0; i < 500; i++ {
+		tasks := 1 + r.Intn(64)
+		failedTask := r.Intn(tasks)
+
+		tt := newTestTasks(map[int]int{failedTask: -1})
+		err := Serial(tt, tasks, 1+r.Intn(8), r.Intn(16))
+		assert.Equal(t, errTaskFailed, err)
+
+		for task, attempts := range tt.attempts {
+			assert.Equal(t, 1, attempts, "task %v executed more than once", task)
+		}
+
+		assert.LessOrEqual(t, len(tt.collected), failedTask)
+		for j, task := range tt.collected {
+			assert.Equal(t, j, task)
+		}
+	}
+
+	waitGoroutines(t, goroutines)
+}
+
+// TestSerialCollectError checks that execution terminates cleanly if failed to collect result.
+func TestSerialCollectError(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	for _, failedTask := range []int{0, 7, 99} {
+		f := failedCollector{failedTask: failedTask}
+		assert.Equal(t, errTaskFailed, Serial(&f, 100, 4, 4))
+		assert.Equal(t, failedTask+1, f.collected)
+	}
+
+	waitGoroutines(t, goroutines)
+}
+
+type failedCollector struct {
+	failedTask int
+	collected  int
+}
+
+func (f *failedCollector) ParallelDo(routine, task int) (interface{}, error) {
+	return nil, nil
+}
+
+func (f *failedCollector) ParallelCollect(result *Result) error {
+	f.collected++
+
+	if result.Task == f.failedTask {
+		return errTaskFailed
+	}
+
+	return nil
+}
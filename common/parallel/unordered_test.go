@@ -0,0 +1,66 @@
+package parallel
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bar struct {
+	inflight    int32
+	maxInflight int32
+	failedTask  int
+	collected   map[int]int
+}
+
+func (b *bar) ParallelDo(routine, task int) (interface{}, error) {
+	inflight := atomic.AddInt32(&b.inflight, 1)
+	defer atomic.AddInt32(&b.inflight, -1)
+
+	for {
+		max := atomic.LoadInt32(&b.maxInflight)
+		if inflight <= max || atomic.CompareAndSwapInt32(&b.maxInflight, max, inflight) {
+			break
+		}
+	}
+
+	if task == b.failedTask {
+		return nil, errors.New("task failed")
+	}
+
+	// complete tasks out of order
+	time.Sleep(time.Duration(task%3) * time.Millisecond)
+
+	return task * task, nil
+}
+
+func (b *bar) ParallelCollect(result *Result) error {
+	b.collected[result.Task] = result.Value.(int)
+	return nil
+}
+
+func TestUnordered(t *testing.T) {
+	b := bar{failedTask: -1, collected: make(map[int]int)}
+
+	tasks := 100
+
+	err := Unordered(&b, tasks, 4, 16)
+	assert.Nil(t, err)
+	assert.Equal(t, tasks, len(b.collected))
+	assert.LessOrEqual(t, b.maxInflight, int32(4))
+
+	for i := 0; i < tasks; i++ {
+		assert.Equal(t, i*i, b.collected[i])
+	}
+}
+
+func TestUnorderedError(t *testing.T) {
+	b := bar{failedTask: 37, collected: make(map[int]int)}
+
+	err := Unordered(&b, 100, 4, 8)
+	assert.EqualError(t, err, "task failed")
+	assert.Less(t, len(b.collected), 100)
+}
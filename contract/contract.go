@@ -78,6 +78,29 @@ func (c *contract) send(method string, args ...interface{}) (common.Hash, error)
 	})
 }
 
+// call calls the view method of contract and returns the unpacked outputs.
+func (c *contract) call(method string, args ...interface{}) ([]interface{}, error) {
+	data, err := c.abi.Pack(method, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to pack ABI data")
+	}
+
+	output, err := c.client.Eth.Call(types.CallRequest{
+		To:   &c.address,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to call %v", method)
+	}
+
+	values, err := c.abi.Unpack(method, output)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to unpack ABI data")
+	}
+
+	return values, nil
+}
+
 func (c *contract) WaitForReceipt(txHash common.Hash) (*types.Receipt, error) {
 	return waitForReceipt(c.client, txHash)
 }
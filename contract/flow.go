@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/openweb3/web3go"
 	"github.com/sirupsen/logrus"
@@ -22,6 +23,25 @@ func (flow *Flow) Submit(submission Submission) (common.Hash, error) {
 	return flow.contract.send("submit", submission)
 }
 
+// GetContext returns the latest mine context of flow.
+func (flow *Flow) GetContext() (*MineContext, error) {
+	values, err := flow.contract.call("getContext")
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.ConvertType(values[0], new(MineContext)).(*MineContext), nil
+}
+
+// MineContext is the context of flow, e.g. flow merkle root and length (in chunks).
+type MineContext struct {
+	Epoch      *big.Int
+	EpochStart *big.Int
+	FlowRoot   [32]byte
+	FlowLength *big.Int
+	Digest     [32]byte
+}
+
 type SubmissionNode struct {
 	Root   [32]byte
 	Height *big.Int // sub-tree height of this node
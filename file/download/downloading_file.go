@@ -5,6 +5,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 const downloadingFileSuffix = ".download"
@@ -13,62 +14,114 @@ type DownloadingFile struct {
 	filename   string
 	underlying *os.File
 	metadata   *Metadata
+	syncPolicy SyncPolicy
 }
 
-func CreateDownloadingFile(filename string, root common.Hash, size int64) (*DownloadingFile, error) {
+// CreateDownloadingFile creates a new downloading file or opens the existing one to resume, and
+// tracks the completion of segments with the specified segment size. If metadata of the existing
+// downloading file corrupted, it will be downloaded from scratch.
+func CreateDownloadingFile(filename string, root common.Hash, size, segmentSize int64) (*DownloadingFile, error) {
 	file, err := os.OpenFile(filename+downloadingFileSuffix, os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
 		return nil, errors.WithMessage(err, "Failed to open file")
 	}
 
+	metadata, err := loadOrCreateMetadata(file, root, size, segmentSize)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if metadata.Root != root {
+		file.Close()
+		return nil, errors.Errorf("Root mismatch, expected = %v, actual = %v", root, metadata.Root)
+	}
+
+	if metadata.Size != size {
+		file.Close()
+		return nil, errors.Errorf("File size mismatch, expected = %v, actual = %v", size, metadata.Size)
+	}
+
+	if metadata.SegmentSize != segmentSize {
+		file.Close()
+		return nil, errors.Errorf("Segment size mismatch, expected = %v, actual = %v", segmentSize, metadata.SegmentSize)
+	}
+
+	return &DownloadingFile{filename, file, metadata, SyncData}, nil
+}
+
+func loadOrCreateMetadata(file *os.File, root common.Hash, size, segmentSize int64) (*Metadata, error) {
 	info, err := file.Stat()
 	if err != nil {
 		return nil, errors.WithMessage(err, "Failed to stat file")
 	}
 
-	var metadata *Metadata
+	if info.Size() > 0 {
+		metadata, err := LoadMetadata(file)
+		if err == nil {
+			if metadata.Version != MetadataVersion {
+				if err = metadata.Extend(file); err != nil {
+					return nil, errors.WithMessagef(err, "Failed to upgrade metadata from version %v", metadata.Version)
+				}
+			}
 
-	if info.Size() == 0 {
-		metadata = NewMetadata(root, size)
-		if err = metadata.Extend(file); err != nil {
-			return nil, errors.WithMessage(err, "Failed to extend metadata")
+			return metadata, nil
 		}
-	} else if metadata, err = LoadMetadata(file); err != nil {
-		return nil, errors.WithMessage(err, "Failed to load metadata")
-	}
 
-	if metadata.Root != root {
-		return nil, errors.Errorf("Root mismatch, expected = %v, actual = %v", root, metadata.Root)
+		if !errors.Is(err, ErrMetadataCorrupted) {
+			return nil, errors.WithMessage(err, "Failed to load metadata")
+		}
+
+		logrus.WithError(err).WithField("file", file.Name()).Warn("Downloading file corrupted, download from scratch")
+
+		if err = file.Truncate(0); err != nil {
+			return nil, errors.WithMessage(err, "Failed to truncate corrupted file")
+		}
 	}
 
-	if metadata.Size != size {
-		return nil, errors.Errorf("File size mismatch, expected = %v, actual = %v", size, metadata.Size)
+	metadata := NewMetadata(root, size, segmentSize)
+	if err = metadata.Extend(file); err != nil {
+		return nil, errors.WithMessage(err, "Failed to extend metadata")
 	}
 
-	return &DownloadingFile{filename, file, metadata}, nil
+	return metadata, nil
 }
 
 func (file *DownloadingFile) Metadata() *Metadata {
 	return file.metadata
 }
 
-func (file *DownloadingFile) Write(data []byte) error {
+// Write writes data of continuous segments from the specified segment index, which could be
+// called in any order of segments.
+func (file *DownloadingFile) Write(segment uint64, data []byte) error {
 	if file.underlying == nil {
 		return errors.New("File already sealed")
 	}
 
-	return file.metadata.Write(file.underlying, data)
+	return file.metadata.Write(file.underlying, segment, data, file.syncPolicy)
+}
+
+// SetSyncPolicy sets the policy to fsync data and metadata, which is SyncData by default.
+func (file *DownloadingFile) SetSyncPolicy(policy SyncPolicy) {
+	file.syncPolicy = policy
 }
 
 func (file *DownloadingFile) Seal() error {
-	if file.metadata.Offset < file.metadata.Size {
-		return errors.Errorf("Download incompleted, offset = %v, size = %v", file.metadata.Offset, file.metadata.Size)
+	if completed, total := file.metadata.NumCompleted(), file.metadata.NumSegments(); completed < total {
+		return errors.Errorf("Download incompleted, completed segments = %v, total = %v", completed, total)
 	}
 
 	if err := file.underlying.Truncate(file.metadata.Size); err != nil {
 		return errors.WithMessage(err, "Failed to truncate metadata")
 	}
 
+	// persist file before renamed
+	if file.syncPolicy >= SyncData {
+		if err := file.underlying.Sync(); err != nil {
+			return errors.WithMessage(err, "Failed to sync downloaded file")
+		}
+	}
+
 	if err := file.underlying.Close(); err != nil {
 		return errors.WithMessage(err, "Failed to close downloading file")
 	}
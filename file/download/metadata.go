@@ -1,25 +1,141 @@
 package download
 
 import (
+	"bytes"
 	"encoding/binary"
+	"hash/crc32"
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 )
 
-const MetadataSize = common.HashLength + 8 + 8
+// Metadata is stored at the end of downloading file, with layout:
+//
+//	[completion bitmap of segments][root 32][size 8][segment size 8][crc 4][version 4][magic 4]
+//
+// where crc is the CRC-32 checksum of all fields before it. Metadata of version 2 has the same
+// layout without crc. Legacy metadata (version 1) only tracks the offset to write sequentially,
+// with layout:
+//
+//	[root 32][size 8][offset 8]
+const (
+	MetadataVersion    = 3
+	MetadataFooterSize = common.HashLength + 8 + 8 + 4 + 4 + 4
+
+	// LegacyMetadataSize is the size of metadata of version 1.
+	LegacyMetadataSize = common.HashLength + 8 + 8
+
+	// legacySegmentSize is the segment size of files downloaded with legacy metadata.
+	legacySegmentSize = 256 * 1024
+)
+
+// footerSizes is the footer size of each supported version which has magic.
+var footerSizes = map[uint32]int{
+	2: MetadataFooterSize - 4,
+	3: MetadataFooterSize,
+}
+
+var metadataMagic = []byte("IODL")
+
+// ErrMetadataCorrupted is returned when metadata is truncated, tampered or not written by this client.
+var ErrMetadataCorrupted = errors.New("Metadata corrupted")
+
+// SyncPolicy decides when to fsync the downloading file.
+type SyncPolicy int
+
+const (
+	// SyncNone never fsync, so segments marked as completed may be lost once system crashed.
+	SyncNone SyncPolicy = iota
+	// SyncData fsync data before metadata updated, so that segments are marked as completed only
+	// if data persisted, but the latest metadata update may be lost once system crashed.
+	SyncData
+	// SyncAll fsync both data and metadata.
+	SyncAll
+)
 
 type Metadata struct {
-	Root   common.Hash // file merkle root
-	Size   int64       // file size to download
-	Offset int64       // offset to write for the next time
+	Root        common.Hash // file merkle root
+	Size        int64       // file size to download
+	SegmentSize int64       // size of segment to track completion
+	Version     uint32      // version of metadata loaded from file
+
+	completed []byte // completion bitmap of segments
+}
+
+func NewMetadata(root common.Hash, size, segmentSize int64) *Metadata {
+	md := Metadata{
+		Root:        root,
+		Size:        size,
+		SegmentSize: segmentSize,
+		Version:     MetadataVersion,
+	}
+
+	md.completed = make([]byte, bitmapSize(md.NumSegments()))
+
+	return &md
+}
+
+func bitmapSize(numSegments uint64) int {
+	return int((numSegments + 7) / 8)
+}
+
+// NumSegments returns the number of segments to download.
+func (md *Metadata) NumSegments() uint64 {
+	return uint64((md.Size + md.SegmentSize - 1) / md.SegmentSize)
+}
+
+// IsCompleted returns whether the specified segment downloaded.
+func (md *Metadata) IsCompleted(segment uint64) bool {
+	return md.completed[segment/8]&(1<<(segment%8)) != 0
 }
 
-func NewMetadata(root common.Hash, size int64) *Metadata {
-	return &Metadata{root, size, 0}
+// NumCompleted returns the number of downloaded segments.
+func (md *Metadata) NumCompleted() uint64 {
+	var completed uint64
+
+	for i := uint64(0); i < md.NumSegments(); i++ {
+		if md.IsCompleted(i) {
+			completed++
+		}
+	}
+
+	return completed
 }
 
+// Completed returns whether all segments downloaded.
+func (md *Metadata) Completed() bool {
+	return md.NumCompleted() == md.NumSegments()
+}
+
+// Missing returns indexes of all segments not downloaded yet.
+func (md *Metadata) Missing() []uint64 {
+	var missing []uint64
+
+	for i := uint64(0); i < md.NumSegments(); i++ {
+		if !md.IsCompleted(i) {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
+
+func (md *Metadata) setCompleted(segment uint64) {
+	md.completed[segment/8] |= 1 << (segment % 8)
+}
+
+// metadataSize returns the size of metadata stored at the end of file.
+func (md *Metadata) metadataSize() int64 {
+	return int64(len(md.completed) + MetadataFooterSize)
+}
+
+func corrupted(format string, args ...interface{}) error {
+	return errors.WithMessagef(ErrMetadataCorrupted, format, args...)
+}
+
+// LoadMetadata loads metadata at the end of file, and metadata of legacy versions are also supported.
+// Returns ErrMetadataCorrupted if metadata is invalid.
 func LoadMetadata(file *os.File) (*Metadata, error) {
 	info, err := file.Stat()
 	if err != nil {
@@ -27,51 +143,184 @@ func LoadMetadata(file *os.File) (*Metadata, error) {
 	}
 
 	size := info.Size()
-	if size < MetadataSize {
-		return nil, errors.Errorf("File size too small %v", size)
+	if size < LegacyMetadataSize {
+		return nil, corrupted("File size too small %v", size)
+	}
+
+	footer := make([]byte, MetadataFooterSize)
+	if size < MetadataFooterSize {
+		footer = footer[:size]
+	}
+
+	if err = readFull(file, footer, size-int64(len(footer))); err != nil {
+		return nil, errors.WithMessage(err, "Failed to read metadata footer from file")
 	}
 
-	// read metadata at the end of file
-	buf := make([]byte, MetadataSize)
-	n, err := file.ReadAt(buf, size-MetadataSize)
+	if !bytes.HasSuffix(footer, metadataMagic) {
+		return loadLegacyMetadata(footer[len(footer)-LegacyMetadataSize:], size)
+	}
+
+	// read the whole metadata by size in footer
+	f, err := deserializeFooter(footer)
 	if err != nil {
+		return nil, err
+	}
+
+	if f.size > size || size != f.size+f.metadataSize() {
+		return nil, corrupted("File size mismatch with metadata, size = %v, fileSize = %v", f.size, size)
+	}
+
+	encoded := make([]byte, f.metadataSize())
+	if err = readFull(file, encoded, f.size); err != nil {
 		return nil, errors.WithMessage(err, "Failed to read metadata from file")
 	}
 
-	if n != MetadataSize {
-		return nil, errors.Errorf("Read metadata length mismatch, expected = %v, actual = %v", MetadataSize, n)
+	return DeserializeMedata(encoded)
+}
+
+func loadLegacyMetadata(encoded []byte, fileSize int64) (*Metadata, error) {
+	root := common.BytesToHash(encoded[:common.HashLength])
+	size := int64(binary.BigEndian.Uint64(encoded[common.HashLength : common.HashLength+8]))
+	offset := int64(binary.BigEndian.Uint64(encoded[common.HashLength+8:]))
+
+	if size < 0 || fileSize != size+LegacyMetadataSize {
+		return nil, corrupted("File size mismatch with legacy metadata, size = %v, fileSize = %v", size, fileSize)
+	}
+
+	if offset < 0 || offset > size || (offset%legacySegmentSize > 0 && offset != size) {
+		return nil, corrupted("Invalid offset in legacy metadata %v", offset)
 	}
 
-	metadata, err := DeserializeMedata(buf)
+	md := NewMetadata(root, size, legacySegmentSize)
+	md.Version = 1
+
+	// data written sequentially before offset
+	for i := uint64(0); i < md.NumSegments() && int64(i)*md.SegmentSize < offset; i++ {
+		md.setCompleted(i)
+	}
+
+	return md, nil
+}
+
+func readFull(file *os.File, buf []byte, offset int64) error {
+	n, err := file.ReadAt(buf, offset)
 	if err != nil {
-		return nil, errors.WithMessage(err, "Failed to deserialize metadata")
+		return err
 	}
 
-	return metadata, nil
+	if n != len(buf) {
+		return errors.Errorf("Read length mismatch, expected = %v, actual = %v", len(buf), n)
+	}
+
+	return nil
 }
 
+// Serialize serializes metadata in the latest version, including completion bitmap and footer.
 func (md *Metadata) Serialize() []byte {
-	encoded := make([]byte, MetadataSize)
+	encoded := make([]byte, 0, md.metadataSize())
 
-	copy(encoded[:common.HashLength], md.Root.Bytes())
-	binary.BigEndian.PutUint64(encoded[common.HashLength:common.HashLength+8], uint64(md.Size))
-	binary.BigEndian.PutUint64(encoded[common.HashLength+8:], uint64(md.Offset))
+	encoded = append(encoded, md.completed...)
+	encoded = append(encoded, md.Root.Bytes()...)
+	encoded = appendUint64(encoded, uint64(md.Size))
+	encoded = appendUint64(encoded, uint64(md.SegmentSize))
+	encoded = appendUint32(encoded, crc32.ChecksumIEEE(encoded))
+	encoded = appendUint32(encoded, MetadataVersion)
+	encoded = append(encoded, metadataMagic...)
 
 	return encoded
 }
 
+func appendUint64(buf []byte, val uint64) []byte {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], val)
+	return append(buf, encoded[:]...)
+}
+
+func appendUint32(buf []byte, val uint32) []byte {
+	var encoded [4]byte
+	binary.BigEndian.PutUint32(encoded[:], val)
+	return append(buf, encoded[:]...)
+}
+
+// DeserializeMedata deserializes metadata of version 2 or later, and returns ErrMetadataCorrupted
+// if metadata is invalid.
 func DeserializeMedata(encoded []byte) (*Metadata, error) {
-	if len(encoded) != MetadataSize {
-		return nil, errors.Errorf("Invalid data length, expected = %v, actual = %v", MetadataSize, len(encoded))
+	f, err := deserializeFooter(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if expected := f.metadataSize(); int64(len(encoded)) != expected {
+		return nil, corrupted("Invalid data length, expected = %v, actual = %v", expected, len(encoded))
+	}
+
+	if f.version >= 3 {
+		crcOffset := len(encoded) - 12
+		if checksum := binary.BigEndian.Uint32(encoded[crcOffset:]); checksum != crc32.ChecksumIEEE(encoded[:crcOffset]) {
+			return nil, corrupted("Checksum mismatch")
+		}
+	}
+
+	md := NewMetadata(f.root, f.size, f.segmentSize)
+	md.Version = f.version
+	copy(md.completed, encoded)
+
+	return md, nil
+}
+
+type footer struct {
+	root        common.Hash
+	size        int64
+	segmentSize int64
+	version     uint32
+	length      int // footer length
+}
+
+// metadataSize returns the size of metadata including completion bitmap and footer.
+func (f *footer) metadataSize() int64 {
+	numSegments := f.size / f.segmentSize
+	if f.size%f.segmentSize > 0 {
+		numSegments++
+	}
+
+	return int64(bitmapSize(uint64(numSegments)) + f.length)
+}
+
+// deserializeFooter deserializes the footer at the end of specified data.
+func deserializeFooter(encoded []byte) (*footer, error) {
+	if len(encoded) < 8 || !bytes.HasSuffix(encoded, metadataMagic) {
+		return nil, corrupted("Invalid magic")
 	}
 
-	return &Metadata{
-		Root:   common.BytesToHash(encoded[:common.HashLength]),
-		Size:   int64(binary.BigEndian.Uint64(encoded[common.HashLength : common.HashLength+8])),
-		Offset: int64(binary.BigEndian.Uint64(encoded[common.HashLength+8:])),
-	}, nil
+	version := binary.BigEndian.Uint32(encoded[len(encoded)-8:])
+	footerSize, ok := footerSizes[version]
+	if !ok {
+		return nil, corrupted("Unsupported version %v", version)
+	}
+
+	if len(encoded) < footerSize {
+		return nil, corrupted("Invalid footer length %v", len(encoded))
+	}
+
+	encoded = encoded[len(encoded)-footerSize:]
+	offset := common.HashLength
+	f := footer{
+		root:        common.BytesToHash(encoded[:offset]),
+		size:        int64(binary.BigEndian.Uint64(encoded[offset : offset+8])),
+		segmentSize: int64(binary.BigEndian.Uint64(encoded[offset+8 : offset+16])),
+		version:     version,
+		length:      footerSize,
+	}
+
+	if f.size < 0 || f.segmentSize <= 0 {
+		return nil, corrupted("Invalid size %v or segment size %v", f.size, f.segmentSize)
+	}
+
+	return &f, nil
 }
 
+// Extend writes metadata in the latest version at the end of file data, which is used to create
+// metadata for a new file, or upgrade metadata of legacy versions.
 func (md *Metadata) Extend(file *os.File) error {
 	info, err := file.Stat()
 	if err != nil {
@@ -79,37 +328,76 @@ func (md *Metadata) Extend(file *os.File) error {
 	}
 
 	// file already truncated and length mismatch with metadata
-	if size := info.Size(); size > 0 && size != md.Size {
+	if size := info.Size(); size > 0 && size < md.Size {
 		return errors.Errorf("Invalid file size, expected = %v, actual = %v", md.Size, size)
 	}
 
-	// extend file with metadata
-	if err = file.Truncate(md.Size + MetadataSize); err != nil {
+	// overwrite the legacy metadata if any, and extend file with metadata
+	if err = md.writeMetadata(file, md.completed); err != nil {
+		return err
+	}
+
+	if err = file.Truncate(md.Size + md.metadataSize()); err != nil {
 		return errors.WithMessage(err, "Failed to truncate file to extend metadata")
 	}
 
-	// write metadata at the end of file
-	n, err := file.WriteAt(md.Serialize(), md.Size)
+	if err = file.Sync(); err != nil {
+		return errors.WithMessage(err, "Failed to sync metadata")
+	}
+
+	md.Version = MetadataVersion
+
+	return nil
+}
+
+// writeMetadata writes metadata with the specified completion bitmap at the end of file data.
+func (md *Metadata) writeMetadata(file *os.File, completed []byte) error {
+	encoded := (&Metadata{
+		Root:        md.Root,
+		Size:        md.Size,
+		SegmentSize: md.SegmentSize,
+		completed:   completed,
+	}).Serialize()
+
+	n, err := file.WriteAt(encoded, md.Size)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to write metadata")
 	}
 
-	if n != MetadataSize {
-		return errors.Errorf("Written metadata length mismatch, expected = %v, actual = %v", MetadataSize, n)
+	if n != len(encoded) {
+		return errors.Errorf("Written metadata length mismatch, expected = %v, actual = %v", len(encoded), n)
 	}
 
 	return nil
 }
 
-func (md *Metadata) Write(file *os.File, data []byte) error {
+// Write writes data of continuous segments from the specified segment index, and marks these
+// segments as completed. Note, data should be aligned with segment size except the last segment.
+//
+// Data is always written before metadata, and fsync depends on the specified policy. So, once
+// system crashed, segments may be downloaded again, but never marked as completed without data
+// persisted unless SyncNone specified.
+func (md *Metadata) Write(file *os.File, segment uint64, data []byte, policy SyncPolicy) error {
+	if md.Version != MetadataVersion {
+		return errors.Errorf("Metadata version %v not upgraded", md.Version)
+	}
+
+	numSegments := uint64((int64(len(data)) + md.SegmentSize - 1) / md.SegmentSize)
+	endSegment := segment + numSegments
+
 	// check boundary
-	if md.Offset+int64(len(data)) > md.Size {
-		return errors.Errorf("Written data out of bound, offset = %v, dataLen = %v, fileSize = %v", md.Offset, len(data), md.Size)
+	offset := int64(segment) * md.SegmentSize
+	if endSegment > md.NumSegments() || offset+int64(len(data)) > md.Size {
+		return errors.Errorf("Written data out of bound, segment = %v, dataLen = %v, fileSize = %v", segment, len(data), md.Size)
+	}
+
+	if int64(len(data))%md.SegmentSize > 0 && offset+int64(len(data)) != md.Size {
+		return errors.Errorf("Written data not aligned with segment, segment = %v, dataLen = %v", segment, len(data))
 	}
 
 	// write data
 	// TODO validate proof to ensure data integrity
-	n, err := file.WriteAt(data, md.Offset)
+	n, err := file.WriteAt(data, offset)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to write data")
 	}
@@ -118,21 +406,29 @@ func (md *Metadata) Write(file *os.File, data []byte) error {
 		return errors.Errorf("Written data length mismatch, expected = %v, actual = %v", len(data), n)
 	}
 
-	// update offset of metadata
-	offset := md.Offset + int64(len(data))
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, uint64(offset))
+	if policy >= SyncData {
+		if err = file.Sync(); err != nil {
+			return errors.WithMessage(err, "Failed to sync data")
+		}
+	}
 
-	n, err = file.WriteAt(buf, md.Size+int64(MetadataSize-len(buf)))
-	if err != nil {
-		return errors.WithMessage(err, "Failed to update offset of metadata")
+	// update completion bitmap of metadata, and never updated in memory if failed to write
+	completed := append([]byte(nil), md.completed...)
+	for i := segment; i < endSegment; i++ {
+		completed[i/8] |= 1 << (i % 8)
 	}
 
-	if n != len(buf) {
-		return errors.Errorf("Written offset length mismatch, expected = %v, actual = %v", len(buf), n)
+	if err = md.writeMetadata(file, completed); err != nil {
+		return errors.WithMessage(err, "Failed to update metadata")
+	}
+
+	if policy >= SyncAll {
+		if err = file.Sync(); err != nil {
+			return errors.WithMessage(err, "Failed to sync metadata")
+		}
 	}
 
-	md.Offset = offset
+	md.completed = completed
 
 	return nil
 }
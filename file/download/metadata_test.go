@@ -1,7 +1,10 @@
 package download
 
 import (
+	"encoding/binary"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -11,17 +14,18 @@ import (
 var testHash = common.HexToHash("0xc8ad6d515dddd96e2e3cf28735944d631621d89f78f3379ffcd0262a6d1f7092")
 
 func TestMetadataSerde(t *testing.T) {
-	md := Metadata{
-		Root:   testHash,
-		Size:   1234567,
-		Offset: 456,
-	}
+	md := NewMetadata(testHash, 1234567, 1024)
+	md.setCompleted(0)
+	md.setCompleted(9)
+	md.setCompleted(md.NumSegments() - 1)
 
 	encoded := md.Serialize()
+	assert.Equal(t, md.metadataSize(), int64(len(encoded)))
 
 	md2, err := DeserializeMedata(encoded)
 	assert.NoError(t, err)
-	assert.Equal(t, md, *md2)
+	assert.Equal(t, *md, *md2)
+	assert.Equal(t, uint64(3), md2.NumCompleted())
 }
 
 func TestMetadata(t *testing.T) {
@@ -36,18 +40,21 @@ func TestMetadata(t *testing.T) {
 	}()
 
 	// extend file with metadata
-	md := NewMetadata(testHash, 12345)
+	md := NewMetadata(testHash, 12345, 1024)
 	assert.NoError(t, md.Extend(tmpFile))
+	assert.Equal(t, uint64(13), md.NumSegments())
 
 	// check file size after metadata extended
 	info, err := tmpFile.Stat()
 	assert.NoError(t, err)
-	assert.Equal(t, md.Size+MetadataSize, info.Size())
+	assert.Equal(t, md.Size+md.metadataSize(), info.Size())
 
-	// write some data and metadata updated
-	data := []byte("hello, world")
-	assert.NoError(t, md.Write(tmpFile, data))
-	assert.Equal(t, int64(len(data)), md.Offset)
+	// write segments out of order and metadata updated
+	assert.NoError(t, md.Write(tmpFile, 12, make([]byte, 12345-12*1024), SyncAll))
+	assert.NoError(t, md.Write(tmpFile, 3, make([]byte, 2*1024), SyncData))
+	assert.Error(t, md.Write(tmpFile, 6, make([]byte, 100), SyncNone))
+	assert.Error(t, md.Write(tmpFile, 12, make([]byte, 1024), SyncNone))
+	assert.Equal(t, []uint64{0, 1, 2, 5, 6, 7, 8, 9, 10, 11}, md.Missing())
 
 	// close and reopen file
 	assert.NoError(t, tmpFile.Close())
@@ -60,3 +67,111 @@ func TestMetadata(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, *md, *md2)
 }
+
+func TestLegacyMetadata(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data"+downloadingFileSuffix)
+	size := int64(3*legacySegmentSize + 100)
+
+	// create downloading file with legacy metadata, and 2 segments downloaded
+	legacy := make([]byte, LegacyMetadataSize)
+	copy(legacy, testHash.Bytes())
+	binary.BigEndian.PutUint64(legacy[common.HashLength:], uint64(size))
+	binary.BigEndian.PutUint64(legacy[common.HashLength+8:], 2*legacySegmentSize)
+	assert.NoError(t, os.WriteFile(filename, append(make([]byte, size), legacy...), 0644))
+
+	file, err := os.Open(filename)
+	assert.NoError(t, err)
+	md, err := LoadMetadata(file)
+	file.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), md.Version)
+	assert.Equal(t, []uint64{2, 3}, md.Missing())
+
+	// upgrade metadata once opened to download
+	df, err := CreateDownloadingFile(filename[:len(filename)-len(downloadingFileSuffix)], testHash, size, legacySegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(MetadataVersion), df.Metadata().Version)
+	assert.NoError(t, df.Write(3, make([]byte, 100)))
+	assert.NoError(t, df.Close())
+
+	file, err = os.Open(filename)
+	assert.NoError(t, err)
+	defer file.Close()
+	md, err = LoadMetadata(file)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(MetadataVersion), md.Version)
+	assert.Equal(t, []uint64{2}, md.Missing())
+}
+
+func TestMetadataVersion2(t *testing.T) {
+	md := NewMetadata(testHash, 12345, 1024)
+	md.setCompleted(3)
+
+	// remove crc and update version
+	encoded := md.Serialize()
+	crcOffset := len(encoded) - 12
+	encoded = append(encoded[:crcOffset], encoded[crcOffset+4:]...)
+	binary.BigEndian.PutUint32(encoded[len(encoded)-8:], 2)
+
+	md2, err := DeserializeMedata(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), md2.Version)
+	assert.Equal(t, md.completed, md2.completed)
+}
+
+func TestMetadataCorrupted(t *testing.T) {
+	md := NewMetadata(testHash, 12345, 1024)
+	encoded := md.Serialize()
+
+	// flip any bit except magic
+	for i := 0; i < len(encoded)-len(metadataMagic); i++ {
+		corrupted := append([]byte(nil), encoded...)
+		corrupted[i] ^= 0x10
+
+		_, err := DeserializeMedata(corrupted)
+		assert.ErrorIs(t, err, ErrMetadataCorrupted, "byte %v", i)
+	}
+
+	// truncated
+	_, err := DeserializeMedata(encoded[1:])
+	assert.ErrorIs(t, err, ErrMetadataCorrupted)
+
+	// foreign file
+	filename := filepath.Join(t.TempDir(), "foreign")
+	foreign := make([]byte, 4096)
+	rand.Read(foreign)
+	assert.NoError(t, os.WriteFile(filename, foreign, 0644))
+
+	file, err := os.Open(filename)
+	assert.NoError(t, err)
+	defer file.Close()
+	_, err = LoadMetadata(file)
+	assert.ErrorIs(t, err, ErrMetadataCorrupted)
+}
+
+func TestDownloadingFileRestart(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data")
+
+	file, err := CreateDownloadingFile(filename, testHash, 12345, 1024)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Write(0, make([]byte, 1024)))
+	assert.NoError(t, file.Close())
+
+	// resume download
+	file, err = CreateDownloadingFile(filename, testHash, 12345, 1024)
+	assert.NoError(t, err)
+	assert.True(t, file.Metadata().IsCompleted(0))
+	assert.NoError(t, file.Close())
+
+	// corrupt the completion bitmap
+	raw, err := os.ReadFile(filename + downloadingFileSuffix)
+	assert.NoError(t, err)
+	raw[12345] ^= 0x02
+	assert.NoError(t, os.WriteFile(filename+downloadingFileSuffix, raw, 0644))
+
+	// download from scratch
+	file, err = CreateDownloadingFile(filename, testHash, 12345, 1024)
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, uint64(0), file.Metadata().NumCompleted())
+}
@@ -3,6 +3,7 @@ package file
 import (
 	"fmt"
 
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
 	"github.com/Ionian-Web3-Storage/ionian-client/common/parallel"
 	"github.com/Ionian-Web3-Storage/ionian-client/file/download"
 	"github.com/Ionian-Web3-Storage/ionian-client/node"
@@ -10,87 +11,278 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const minBufSize = 8
+const (
+	minBufSize = 8
+
+	// maxDownloadRetries is the maximum retries on other storage nodes to download a batch of segments.
+	maxDownloadRetries = 3
+)
+
+// errInvalidSegment is returned if the downloaded segment failed to validate with proof, which
+// could be retried on other storage nodes.
+var errInvalidSegment = errors.New("invalid segment")
 
 type SegmentDownloader struct {
-	clients []*node.Client
-	file    *download.DownloadingFile
+	pool   *node.Pool
+	file   *download.DownloadingFile
+	layout Layout
+	proof  bool // whether to download segments with proof and validate against the file root
 
-	segmentOffset uint32
-	numChunks     uint32
-	numSegments   uint32
+	numChunks         uint32
+	numSegments       uint32
+	numPaddedChunks   uint32         // leaf nodes of file merkle tree if built by chunks
+	numPaddedSegments uint32         // leaf nodes of file merkle tree
+	tasks             []segmentRange // ranges of missing segments, each task downloads a range
 }
 
-func NewSegmentDownloader(clients []*node.Client, file *download.DownloadingFile) (*SegmentDownloader, error) {
-	offset := file.Metadata().Offset
-	if offset%DefaultSegmentSize > 0 {
-		return nil, errors.Errorf("Invalid data offset in downloading file %v", offset)
+// segmentRange is the segment index range [start, end) to download in a JSON-RPC batch.
+type segmentRange struct {
+	start uint32
+	end   uint32
+}
+
+// NewSegmentDownloader creates a downloader to download missing segments of file in batches of
+// option.BatchSize segments, where zero value fields of option.Layout use the default layout.
+func NewSegmentDownloader(pool *node.Pool, file *download.DownloadingFile, option DownloadOption) (*SegmentDownloader, error) {
+	l := layoutOrDefault(option.Layout)
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	if segmentSize := file.Metadata().SegmentSize; segmentSize != int64(l.SegmentSize()) {
+		return nil, errors.Errorf("Invalid segment size in downloading file %v", segmentSize)
+	}
+
+	if option.BatchSize <= 0 {
+		return nil, errors.Errorf("Invalid batch size %v", option.BatchSize)
 	}
 
 	fileSize := file.Metadata().Size
+	numChunks := numSplits(fileSize, l.ChunkSize)
+	numPaddedChunks := uint32(l.Padding(numChunks))
 
 	return &SegmentDownloader{
-		clients: clients,
-		file:    file,
+		pool:   pool,
+		file:   file,
+		layout: l,
+		proof:  option.Proof,
 
-		segmentOffset: uint32(offset / DefaultSegmentSize),
-		numChunks:     numSplits(fileSize, DefaultChunkSize),
-		numSegments:   numSplits(fileSize, DefaultSegmentSize),
+		numChunks:         numChunks,
+		numSegments:       numSplits(fileSize, l.SegmentSize()),
+		numPaddedChunks:   numPaddedChunks,
+		numPaddedSegments: numSplits(int64(numPaddedChunks), l.SegmentMaxChunks),
+		tasks:             splitMissingSegments(file.Metadata().Missing(), uint32(option.BatchSize)),
 	}, nil
 }
 
-// Download downloads segments in parallel.
+// splitMissingSegments splits missing segments into continuous ranges, and each range has
+// batchSize segments at most.
+func splitMissingSegments(missing []uint64, batchSize uint32) []segmentRange {
+	var ranges []segmentRange
+
+	for _, v := range missing {
+		segment := uint32(v)
+
+		if n := len(ranges); n > 0 && ranges[n-1].end == segment && ranges[n-1].end-ranges[n-1].start < batchSize {
+			ranges[n-1].end++
+		} else {
+			ranges = append(ranges, segmentRange{segment, segment + 1})
+		}
+	}
+
+	return ranges
+}
+
+// Download downloads missing segments in parallel, and segments are written in any order.
 func (downloader *SegmentDownloader) Download() error {
-	numTasks := downloader.numSegments - downloader.segmentOffset
-	numNodes := len(downloader.clients)
+	numNodes := len(downloader.pool.Healthy())
+	if numNodes == 0 {
+		return errors.New("No healthy storage node available")
+	}
 	bufSize := numNodes * 2
 	if bufSize < minBufSize {
 		bufSize = minBufSize
 	}
 
-	return parallel.Serial(downloader, int(numTasks), numNodes, bufSize)
+	return parallel.Unordered(downloader, len(downloader.tasks), numNodes, bufSize)
 }
 
-// ParallelDo implements the parallel.Interface interface.
+// ParallelDo implements the parallel.Interface interface, which downloads a batch of segments.
 func (downloader *SegmentDownloader) ParallelDo(routine, task int) (interface{}, error) {
-	segmentIndex := downloader.segmentOffset + uint32(task)
-	startIndex := segmentIndex * DefaultSegmentMaxChunks
-	endIndex := startIndex + DefaultSegmentMaxChunks
-	if endIndex > downloader.numChunks {
-		endIndex = downloader.numChunks
-	}
+	startSegment, endSegment := downloader.tasks[task].start, downloader.tasks[task].end
 
-	// TODO download with proof and validate
-	root := downloader.file.Metadata().Root
-	segment, err := downloader.clients[routine].DownloadSegment(root, startIndex, endIndex)
+	data, client, err := downloader.downloadWithRetry(startSegment, endSegment)
+	if err == nil {
+		metrics.DownloadSegments.WithLabelValues(client.URL()).Add(float64(endSegment - startSegment))
+		metrics.DownloadBytes.WithLabelValues(client.URL()).Add(float64(len(data)))
+	}
 
 	// remove paddings for the last chunk
-	if segmentIndex == downloader.numSegments-1 && err == nil {
-		fileSize := downloader.file.Metadata().Size
-		if lastChunkSize := fileSize % DefaultChunkSize; lastChunkSize > 0 {
-			paddings := DefaultChunkSize - lastChunkSize
-			segment = segment[0 : len(segment)-int(paddings)]
-		}
+	if endSegment == downloader.numSegments && err == nil {
+		data = downloader.layout.trimLastChunk(data, downloader.file.Metadata().Size)
 	}
 
 	if err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
-			"routine": routine,
-			"segment": fmt.Sprintf("%v/%v", segmentIndex, downloader.numSegments),
-			"chunks":  fmt.Sprintf("[%v, %v)", startIndex, endIndex),
-		}).Error("Failed to download segment")
+			"routine":  routine,
+			"segments": fmt.Sprintf("[%v, %v)/%v", startSegment, endSegment, downloader.numSegments),
+		}).Error("Failed to download segments")
 	} else if logrus.IsLevelEnabled(logrus.TraceLevel) {
 		logrus.WithFields(logrus.Fields{
-			"routine": routine,
-			"segment": fmt.Sprintf("%v/%v", segmentIndex, downloader.numSegments),
-			"chunks":  fmt.Sprintf("[%v, %v)", startIndex, endIndex),
-		}).Trace("Succeeded to download segment")
+			"routine":  routine,
+			"node":     client.URL(),
+			"segments": fmt.Sprintf("[%v, %v)/%v", startSegment, endSegment, downloader.numSegments),
+		}).Trace("Succeeded to download segments")
+	}
+
+	return data, err
+}
+
+// downloadWithRetry downloads segments [start, end) from storage nodes selected in pool, and retries
+// on other storage nodes for retryable errors or invalid segments. Returns the concatenated data of
+// all segments.
+func (downloader *SegmentDownloader) downloadWithRetry(start, end uint32) ([]byte, *node.Client, error) {
+	var failed []*node.Client // nodes failed to download the segments, which are not retried
+	var lastErr error
+
+	for i := 0; ; i++ {
+		client := downloader.pool.Select(failed...)
+		if client == nil && lastErr != nil {
+			return nil, nil, errors.WithMessage(lastErr, "Failed to download segments from all healthy storage nodes")
+		}
+
+		if client == nil {
+			return nil, nil, errors.New("No healthy storage node available")
+		}
+
+		data, err := downloader.downloadSegments(client, start, end)
+		if err == nil {
+			return data, client, nil
+		}
+
+		retryable := node.IsRetryable(err) || errors.Is(err, errInvalidSegment)
+		if !retryable || i >= maxDownloadRetries {
+			return nil, client, errors.WithMessagef(err, "Failed to download segments from node %v", client.URL())
+		}
+
+		failed, lastErr = append(failed, client), err
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"node":     client.URL(),
+			"segments": fmt.Sprintf("[%v, %v)", start, end),
+			"retry":    i + 1,
+		}).Warn("Failed to download segments, retry on another node")
+	}
+}
+
+// downloadSegments downloads segments [start, end) in a JSON-RPC batch if more than one segment
+// specified.
+func (downloader *SegmentDownloader) downloadSegments(client *node.Client, start, end uint32) ([]byte, error) {
+	if downloader.proof {
+		return downloader.downloadSegmentsWithProof(client, start, end)
+	}
+
+	root := downloader.file.Metadata().Root
+
+	var ranges []node.ChunkRange
+	for i := start; i < end; i++ {
+		startIndex, endIndex := downloader.layout.segmentChunks(i, downloader.numChunks)
+		ranges = append(ranges, node.ChunkRange{StartIndex: startIndex, EndIndex: endIndex})
+	}
+
+	if len(ranges) == 1 {
+		return client.DownloadSegment(root, ranges[0].StartIndex, ranges[0].EndIndex)
+	}
+
+	segments, errs, err := client.DownloadSegments(root, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for i, v := range segments {
+		if errs[i] != nil {
+			return nil, errors.WithMessagef(errs[i], "Failed to download chunks [%v, %v)", ranges[i].StartIndex, ranges[i].EndIndex)
+		}
+
+		data = append(data, v...)
+	}
+
+	return data, nil
+}
+
+// downloadSegmentsWithProof downloads segments [start, end) along with proofs in a JSON-RPC batch
+// if more than one segment specified, and validates each segment against the file root.
+func (downloader *SegmentDownloader) downloadSegmentsWithProof(client *node.Client, start, end uint32) ([]byte, error) {
+	root := downloader.file.Metadata().Root
+
+	var segments []*node.SegmentWithProof
+	var errs []error
+
+	if end-start == 1 {
+		segment, err := client.DownloadSegmentWithProof(root, start)
+		segments, errs = []*node.SegmentWithProof{segment}, []error{err}
+	} else {
+		var indices []uint32
+		for i := start; i < end; i++ {
+			indices = append(indices, i)
+		}
+
+		var err error
+		if segments, errs, err = client.DownloadSegmentsWithProof(root, indices); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	for i, v := range segments {
+		index := start + uint32(i)
+
+		if errs[i] != nil {
+			return nil, errors.WithMessagef(errs[i], "Failed to download segment %v", index)
+		}
+
+		if err := downloader.validateSegment(index, v); err != nil {
+			return nil, errors.WithMessagef(err, "Failed to validate segment %v", index)
+		}
+
+		data = append(data, v.Data...)
+	}
+
+	return data, nil
+}
+
+// validateSegment validates the downloaded segment with proof against the file root, where the
+// segment data contains chunks of file only, and the last chunk is padded with zeros.
+func (downloader *SegmentDownloader) validateSegment(index uint32, segment *node.SegmentWithProof) error {
+	if segment == nil {
+		return errors.WithMessage(errInvalidSegment, "segment not found")
+	}
+
+	root := downloader.file.Metadata().Root
+	if segment.Root != root || segment.Index != index {
+		return errors.WithMessagef(errInvalidSegment, "segment mismatch, root = %v, index = %v", segment.Root, segment.Index)
+	}
+
+	startChunk, endChunk := downloader.layout.segmentChunks(index, downloader.numChunks)
+	if size := int(endChunk-startChunk) * downloader.layout.ChunkSize; len(segment.Data) != size {
+		return errors.WithMessagef(errInvalidSegment, "data size mismatch, expected = %v, actual = %v", size, len(segment.Data))
+	}
+
+	// segment root in file merkle tree is calculated with flow padding chunks
+	_, paddedEndChunk := downloader.layout.segmentChunks(index, downloader.numPaddedChunks)
+	padded := make([]byte, int(paddedEndChunk-startChunk)*downloader.layout.ChunkSize)
+	copy(padded, segment.Data)
+
+	segRoot := downloader.layout.segmentRoot(padded)
+	if err := segment.Proof.ValidateHash(root, segRoot, index, downloader.numPaddedSegments, downloader.layout.Hasher); err != nil {
+		return errors.WithMessage(errInvalidSegment, err.Error())
 	}
 
-	return segment, err
+	return nil
 }
 
 // ParallelCollect implements the parallel.Interface interface.
 func (downloader *SegmentDownloader) ParallelCollect(result *parallel.Result) error {
-	return downloader.file.Write(result.Value.([]byte))
+	return downloader.file.Write(uint64(downloader.tasks[result.Task].start), result.Value.([]byte))
 }
@@ -10,8 +10,23 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// DownloadOption is the option to download file from storage nodes.
+type DownloadOption struct {
+	BatchSize int    // number of segments to download in a JSON-RPC batch, 1 to download segments one by one
+	Layout    Layout // file layout, zero value fields use the default layout
+	Proof     bool   // whether to download segments with merkle proof and validate each against the file root
+}
+
+// DefaultDownloadOption returns the default option to download file.
+func DefaultDownloadOption() DownloadOption {
+	return DownloadOption{
+		BatchSize: 4,
+	}
+}
+
 type Downloader struct {
-	clients []*node.Client
+	pool   *node.Pool
+	option DownloadOption
 }
 
 func NewDownloader(clients ...*node.Client) *Downloader {
@@ -19,14 +34,40 @@ func NewDownloader(clients ...*node.Client) *Downloader {
 		panic("storage node not specified")
 	}
 
+	pool, err := node.NewPool(clients)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return NewDownloaderWithPool(pool)
+}
+
+// NewDownloaderWithPool creates a downloader to download segments from healthy storage nodes in pool.
+func NewDownloaderWithPool(pool *node.Pool, option ...DownloadOption) *Downloader {
+	opt := DefaultDownloadOption()
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 1
+	}
+
+	opt.Layout = layoutOrDefault(opt.Layout)
+
 	return &Downloader{
-		clients: clients,
+		pool:   pool,
+		option: opt,
 	}
 }
 
 func (downloader *Downloader) Download(root, filename string) error {
 	hash := common.HexToHash(root)
 
+	if err := downloader.option.Layout.Validate(); err != nil {
+		return errors.WithMessage(err, "Invalid layout")
+	}
+
 	// Query file info from storage node
 	info, err := downloader.queryFile(hash)
 	if err != nil {
@@ -51,9 +92,69 @@ func (downloader *Downloader) Download(root, filename string) error {
 	return nil
 }
 
+// DownloadByTxSeq downloads file of the specified transaction sequence number, which is resolved
+// to the file merkle root by healthy storage nodes, and the downloaded file is validated against
+// the resolved root.
+func (downloader *Downloader) DownloadByTxSeq(txSeq uint64, filename string) error {
+	root, err := downloader.resolveTxSeq(txSeq)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to resolve tx seq")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"txSeq": txSeq,
+		"root":  root,
+	}).Info("Tx seq resolved to file root")
+
+	return downloader.Download(root.Hex(), filename)
+}
+
+// resolveTxSeq queries file merkle root of the specified tx seq on all healthy storage nodes, and
+// requires the root consistent on storage nodes that file found.
+func (downloader *Downloader) resolveTxSeq(txSeq uint64) (common.Hash, error) {
+	clients := downloader.pool.Healthy()
+	if len(clients) == 0 {
+		return common.Hash{}, errors.New("No healthy storage node available")
+	}
+
+	var found *node.FileInfo
+
+	for _, v := range clients {
+		info, err := v.GetFileInfoByTxSeq(txSeq)
+		if err != nil {
+			return common.Hash{}, errors.WithMessagef(err, "Failed to get file info on node %v", v.URL())
+		}
+
+		if info == nil {
+			continue
+		}
+
+		if info.Tx.Seq != txSeq {
+			return common.Hash{}, errors.Errorf("Tx seq mismatch on node %v, expected = %v, actual = %v", v.URL(), txSeq, info.Tx.Seq)
+		}
+
+		if found != nil && found.Tx.DataMerkleRoot != info.Tx.DataMerkleRoot {
+			return common.Hash{}, errors.Errorf("Root mismatch on node %v, expected = %v, actual = %v", v.URL(), found.Tx.DataMerkleRoot, info.Tx.DataMerkleRoot)
+		}
+
+		found = info
+	}
+
+	if found == nil {
+		return common.Hash{}, errors.New("File not found on any storage node")
+	}
+
+	return found.Tx.DataMerkleRoot, nil
+}
+
 func (downloader *Downloader) queryFile(root common.Hash) (info *node.FileInfo, err error) {
-	// requires file finalized on all storage nodes
-	for _, v := range downloader.clients {
+	clients := downloader.pool.Healthy()
+	if len(clients) == 0 {
+		return nil, errors.New("No healthy storage node available")
+	}
+
+	// requires file finalized on all healthy storage nodes
+	for _, v := range clients {
 		info, err = v.GetFileInfo(root)
 		if err != nil {
 			return nil, errors.WithMessagef(err, "Failed to get file info on node %v", v.URL())
@@ -74,7 +175,7 @@ func (downloader *Downloader) queryFile(root common.Hash) (info *node.FileInfo,
 }
 
 func (downloader *Downloader) checkExistence(filename string, hash common.Hash) error {
-	file, err := Open(filename)
+	file, err := Open(filename, downloader.option.Layout)
 	if os.IsNotExist(err) {
 		return nil
 	}
@@ -98,15 +199,19 @@ func (downloader *Downloader) checkExistence(filename string, hash common.Hash)
 }
 
 func (downloader *Downloader) downloadFile(filename string, root common.Hash, size int64) error {
-	file, err := download.CreateDownloadingFile(filename, root, size)
+	file, err := download.CreateDownloadingFile(filename, root, size, int64(downloader.option.Layout.SegmentSize()))
 	if err != nil {
 		return errors.WithMessage(err, "Failed to create downloading file")
 	}
 	defer file.Close()
 
-	logrus.WithField("threads", len(downloader.clients)).Info("Begin to download file from storage node")
+	logrus.WithFields(logrus.Fields{
+		"threads": len(downloader.pool.Healthy()),
+		"batch":   downloader.option.BatchSize,
+		"proof":   downloader.option.Proof,
+	}).Info("Begin to download file from storage node")
 
-	sd, err := NewSegmentDownloader(downloader.clients, file)
+	sd, err := NewSegmentDownloader(downloader.pool, file, downloader.option)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to create segment downloader")
 	}
@@ -125,7 +230,7 @@ func (downloader *Downloader) downloadFile(filename string, root common.Hash, si
 }
 
 func (downloader *Downloader) validateDownloadFile(root, filename string, fileSize int64) error {
-	file, err := Open(filename)
+	file, err := Open(filename, downloader.option.Layout)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to open file")
 	}
@@ -0,0 +1,250 @@
+package file
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/download"
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDownloadService struct {
+	data []byte
+}
+
+func (s *testDownloadService) DownloadSegment(root common.Hash, startIndex, endIndex uint32) ([]byte, error) {
+	// complete segments out of order
+	time.Sleep(time.Duration(startIndex/DefaultSegmentMaxChunks%3) * time.Millisecond)
+
+	data := make([]byte, (endIndex-startIndex)*DefaultChunkSize)
+	copy(data, s.data[startIndex*DefaultChunkSize:])
+
+	return data, nil
+}
+
+func TestSplitMissingSegments(t *testing.T) {
+	assert.Nil(t, splitMissingSegments(nil, 4))
+	assert.Equal(t, []segmentRange{{0, 3}, {5, 6}, {7, 9}}, splitMissingSegments([]uint64{0, 1, 2, 5, 7, 8}, 4))
+	assert.Equal(t, []segmentRange{{0, 2}, {2, 4}, {4, 5}}, splitMissingSegments([]uint64{0, 1, 2, 3, 4}, 2))
+}
+
+func TestSegmentDownloader(t *testing.T) {
+	data := make([]byte, 9*DefaultSegmentSize+1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &testDownloadService{data}))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	pool, err := node.NewPool([]*node.Client{node.MustNewClient(httpServer.URL)})
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	filename := filepath.Join(t.TempDir(), "data")
+	file, err := download.CreateDownloadingFile(filename, common.Hash{}, int64(len(data)), DefaultSegmentSize)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	// some segments already downloaded
+	assert.NoError(t, file.Write(4, data[4*DefaultSegmentSize:6*DefaultSegmentSize]))
+
+	downloader, err := NewSegmentDownloader(pool, file, DownloadOption{BatchSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []segmentRange{{0, 2}, {2, 4}, {6, 8}, {8, 10}}, downloader.tasks)
+	assert.NoError(t, downloader.Download())
+	assert.NoError(t, file.Seal())
+
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, downloaded))
+}
+
+type testTxSeqService struct {
+	infos map[uint64]*node.FileInfo
+}
+
+func (s *testTxSeqService) GetFileInfoByTxSeq(txSeq uint64) (*node.FileInfo, error) {
+	return s.infos[txSeq], nil
+}
+
+func newTestTxSeqClient(t *testing.T, infos map[uint64]*node.FileInfo) *node.Client {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &testTxSeqService{infos}))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	return node.MustNewClient(httpServer.URL)
+}
+
+func TestResolveTxSeq(t *testing.T) {
+	root1, root2 := common.HexToHash("0x01"), common.HexToHash("0x02")
+	info := func(seq uint64, root common.Hash) *node.FileInfo {
+		return &node.FileInfo{Tx: node.Transaction{Seq: seq, DataMerkleRoot: root}}
+	}
+
+	downloader := NewDownloader(
+		newTestTxSeqClient(t, map[uint64]*node.FileInfo{1: info(1, root1), 2: info(2, root2)}),
+		newTestTxSeqClient(t, map[uint64]*node.FileInfo{1: info(1, root1), 2: info(2, root1), 3: info(3, root2)}),
+	)
+
+	root, err := downloader.resolveTxSeq(1)
+	assert.NoError(t, err)
+	assert.Equal(t, root1, root)
+
+	// not found on some nodes
+	root, err = downloader.resolveTxSeq(3)
+	assert.NoError(t, err)
+	assert.Equal(t, root2, root)
+
+	// root mismatch
+	_, err = downloader.resolveTxSeq(2)
+	assert.Error(t, err)
+
+	// not found on any node
+	_, err = downloader.resolveTxSeq(4)
+	assert.Error(t, err)
+}
+
+// testInternalError is a retryable JSON-RPC internal error.
+type testInternalError struct{}
+
+func (testInternalError) Error() string  { return "Internal error" }
+func (testInternalError) ErrorCode() int { return -32603 }
+
+type testFailedDownloadService struct {
+	mu    sync.Mutex
+	calls map[uint32]int // start chunk index -> calls
+}
+
+func (s *testFailedDownloadService) DownloadSegment(root common.Hash, startIndex, endIndex uint32) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[startIndex]++
+
+	return nil, testInternalError{}
+}
+
+func TestSegmentDownloaderRetryOtherNode(t *testing.T) {
+	data := make([]byte, 5*DefaultSegmentSize+1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	failed := testFailedDownloadService{calls: make(map[uint32]int)}
+	var clients []*node.Client
+	for _, service := range []interface{}{&failed, &testDownloadService{data}} {
+		server := rpc.NewServer()
+		assert.NoError(t, server.RegisterName("ionian", service))
+		httpServer := httptest.NewServer(server)
+		defer httpServer.Close()
+
+		clients = append(clients, node.MustNewClient(httpServer.URL, node.ClientOption{Timeout: time.Second}))
+	}
+
+	pool, err := node.NewPool(clients, node.PoolOption{MaxErrorRate: 1})
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	filename := filepath.Join(t.TempDir(), "data")
+	file, err := download.CreateDownloadingFile(filename, common.Hash{}, int64(len(data)), DefaultSegmentSize)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	downloader, err := NewSegmentDownloader(pool, file, DownloadOption{BatchSize: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, downloader.Download())
+	assert.NoError(t, file.Seal())
+
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, downloaded))
+
+	// failed node never retried for the same segment
+	for start, calls := range failed.calls {
+		assert.Equal(t, 1, calls, "start = %v", start)
+	}
+}
+
+// testProofDownloadService serves segments with proof, and corrupts data if required.
+type testProofDownloadService struct {
+	file    *File
+	tree    *merkle.Tree
+	corrupt bool
+}
+
+func (s *testProofDownloadService) DownloadSegmentWithProof(root common.Hash, index uint32) (*node.SegmentWithProof, error) {
+	start, end := s.file.layout.segmentChunks(index, s.file.NumChunks())
+	data, err := s.file.ReadChunks(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.corrupt {
+		data[0]++
+	}
+
+	return &node.SegmentWithProof{Root: root, Data: data, Index: index, Proof: s.tree.ProofAt(int(index))}, nil
+}
+
+func TestSegmentDownloaderWithProof(t *testing.T) {
+	file, data := createTestFile(t, 5*DefaultSegmentSize+1000)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	newClient := func(corrupt bool) *node.Client {
+		server := rpc.NewServer()
+		assert.NoError(t, server.RegisterName("ionian", &testProofDownloadService{file, tree, corrupt}))
+		httpServer := httptest.NewServer(server)
+		t.Cleanup(httpServer.Close)
+
+		return node.MustNewClient(httpServer.URL, node.ClientOption{Timeout: time.Second})
+	}
+
+	downloadFile := func(clients ...*node.Client) ([]byte, error) {
+		pool, err := node.NewPool(clients, node.PoolOption{MaxErrorRate: 1})
+		assert.NoError(t, err)
+		defer pool.Close()
+
+		filename := filepath.Join(t.TempDir(), "data")
+		file, err := download.CreateDownloadingFile(filename, tree.Root(), int64(len(data)), DefaultSegmentSize)
+		assert.NoError(t, err)
+		defer file.Close()
+
+		downloader, err := NewSegmentDownloader(pool, file, DownloadOption{BatchSize: 2, Proof: true})
+		assert.NoError(t, err)
+
+		if err = downloader.Download(); err != nil {
+			return nil, err
+		}
+
+		assert.NoError(t, file.Seal())
+
+		return os.ReadFile(filename)
+	}
+
+	downloaded, err := downloadFile(newClient(false))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, downloaded))
+
+	// invalid segments retried on other node
+	downloaded, err = downloadFile(newClient(true), newClient(false))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, downloaded))
+
+	// invalid segments on all nodes
+	_, err = downloadFile(newClient(true))
+	assert.Error(t, err)
+}
@@ -3,7 +3,10 @@ package file
 import (
 	"errors"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
 	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -24,6 +27,8 @@ var ErrFileRequired = errors.New("file required")
 type File struct {
 	os.FileInfo
 	underlying *os.File
+	path       string // absolute path to cache merkle tree
+	layout     Layout
 }
 
 func Exists(name string) (bool, error) {
@@ -37,7 +42,13 @@ func Exists(name string) (bool, error) {
 	return true, err
 }
 
-func Open(name string) (*File, error) {
+// Open opens the file with the specified layout, or the default layout if not specified.
+func Open(name string, layout ...Layout) (*File, error) {
+	l := layoutOrDefault(layout...)
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
 	file, err := os.Open(name)
 	if err != nil {
 		return nil, err
@@ -49,12 +60,21 @@ func Open(name string) (*File, error) {
 	}
 
 	if info.IsDir() {
+		file.Close()
 		return nil, ErrFileRequired
 	}
 
+	path, err := filepath.Abs(name)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
 	return &File{
 		FileInfo:   info,
 		underlying: file,
+		path:       path,
+		layout:     l,
 	}, nil
 }
 
@@ -62,24 +82,50 @@ func (file *File) Close() error {
 	return file.underlying.Close()
 }
 
+// Layout returns the layout of file.
+func (file *File) Layout() Layout {
+	return file.layout
+}
+
 func (file *File) NumChunks() uint32 {
-	return numSplits(file.Size(), DefaultChunkSize)
+	return numSplits(file.Size(), file.layout.ChunkSize)
 }
 
 func (file *File) NumSegments() uint32 {
-	return numSplits(file.Size(), DefaultChunkSize*DefaultSegmentMaxChunks)
+	return numSplits(file.Size(), file.layout.SegmentSize())
 }
 
 func (file *File) Iterate(flowPadding bool) *Iterator {
 	// File root and the Flow submission has different ways in file padding
-	return NewSegmentIterator(file.underlying, file.Size(), 0, flowPadding)
+	return NewSegmentIterator(file.underlying, file.Size(), 0, flowPadding, file.layout)
 }
 
+// MerkleTree returns the merkle tree of file, which is loaded from cache if MerkleCacheDir of layout
+// specified and file not changed.
 func (file *File) MerkleTree() (*merkle.Tree, error) {
+	cacheEnabled := file.treeCacheEnabled()
+	if cacheEnabled {
+		if tree := file.loadCachedTree(); tree != nil {
+			metrics.MerkleCacheHits.Inc()
+			return tree, nil
+		}
+
+		metrics.MerkleCacheMisses.Inc()
+	}
+
+	start := time.Now()
+
 	iter := file.Iterate(true)
-	var builder merkle.TreeBuilder
+	builder := merkle.TreeBuilder{Hasher: file.layout.Hasher}
+	var segmentRoots []common.Hash
+
+	// empty file is padded with a zero chunk by iterator
+	numDataSegments := file.NumSegments()
+	if numDataSegments == 0 {
+		numDataSegments = 1
+	}
 
-	for {
+	for i := uint32(0); i < numDataSegments; i++ {
 		ok, err := iter.Next()
 		if err != nil {
 			return nil, err
@@ -89,34 +135,42 @@ func (file *File) MerkleTree() (*merkle.Tree, error) {
 			break
 		}
 
-		segRoot := segmentRoot(iter.Current())
+		segRoot := file.layout.segmentRoot(iter.Current())
 
 		builder.AppendHash(segRoot)
+		segmentRoots = append(segmentRoots, segRoot)
 	}
 
-	return builder.Build(), nil
-}
+	// segments of padding chunks only are not read from file
+	numPaddedChunks := file.NumPaddedChunks()
+	for i := numDataSegments; ; i++ {
+		chunkStart, chunkEnd := file.layout.segmentChunks(i, numPaddedChunks)
+		if chunkStart >= chunkEnd {
+			break
+		}
 
-func numSplits(total int64, unit int) uint32 {
-	if total%int64(unit) == 0 {
-		return uint32(total / int64(unit))
+		segRoot := file.layout.zeroSegmentRoot(int(chunkEnd - chunkStart))
+
+		builder.AppendHash(segRoot)
+		segmentRoots = append(segmentRoots, segRoot)
 	}
 
-	return uint32(total/int64(unit)) + 1
-}
+	tree := builder.Build()
 
-func segmentRoot(chunks []byte) common.Hash {
-	dataLen := len(chunks)
-	if dataLen == 0 {
-		return common.Hash{}
+	metrics.MerkleHashedBytes.Add(float64(file.Size()))
+	metrics.MerkleHashDuration.Observe(metrics.Since(start))
+
+	if cacheEnabled && tree != nil {
+		file.cacheTree(segmentRoots, tree.Root())
 	}
 
-	var builder merkle.TreeBuilder
+	return tree, nil
+}
 
-	for offset := 0; offset < dataLen; offset += DefaultChunkSize {
-		chunk := chunks[offset : offset+DefaultChunkSize]
-		builder.Append(chunk)
+func numSplits(total int64, unit int) uint32 {
+	if total%int64(unit) == 0 {
+		return uint32(total / int64(unit))
 	}
 
-	return builder.Build().Root()
+	return uint32(total/int64(unit)) + 1
 }
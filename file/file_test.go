@@ -0,0 +1,32 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentRootPadding(t *testing.T) {
+	layout := layoutOrDefault()
+
+	for _, numChunks := range []int{1, 5, 16, DefaultSegmentMaxChunks} {
+		for _, dataSize := range []int{0, 1, DefaultChunkSize, DefaultChunkSize + 1, numChunks*DefaultChunkSize - 1, numChunks * DefaultChunkSize} {
+			if dataSize > numChunks*DefaultChunkSize {
+				continue
+			}
+
+			chunks := make([]byte, numChunks*DefaultChunkSize)
+			for i := 0; i < dataSize; i++ {
+				chunks[i] = byte(i%255 + 1)
+			}
+
+			var builder merkle.TreeBuilder
+			for _, chunk := range splitChunks(chunks) {
+				builder.Append(chunk)
+			}
+
+			assert.Equal(t, builder.Build().Root(), layout.segmentRoot(chunks), "chunks = %v, data = %v", numChunks, dataSize)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/Ionian-Web3-Storage/ionian-client/contract"
 	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,7 +32,7 @@ func (flow *Flow) CreateSubmission() (*contract.Submission, error) {
 			return nil, err
 		}
 		submission.Nodes = append(submission.Nodes, *node)
-		offset += chunks * DefaultChunkSize
+		offset += chunks * int64(flow.file.layout.ChunkSize)
 	}
 
 	return &submission, nil
@@ -65,13 +67,27 @@ func computePaddedSize(chunks uint32) (uint64, uint64) {
 	return paddedChunks, chunksNextPow2
 }
 
-// e.g. 64, 32, 1 in chunks
 func (flow *Flow) splitNodes() []int64 {
+	chunks := flow.file.NumChunks()
+	nodes := splitNodes(chunks, flow.file.layout.Padding(chunks))
+
+	logrus.WithFields(logrus.Fields{
+		"chunks":   chunks,
+		"nodeSize": nodes,
+	}).Debug("SplitNodes")
+
+	return nodes
+}
+
+// splitNodes splits padded chunks into power of 2 sub trees in descending order, e.g. 64, 32, 1 in
+// chunks, so that each sub tree is aligned in flow if the first one is aligned.
+func splitNodes(chunks uint32, paddedChunks uint64) []int64 {
 	var nodes []int64
 
-	chunks := flow.file.NumChunks()
-	paddedChunks, chunksNextPow2 := computePaddedSize(chunks)
-	nextChunkSize := chunksNextPow2
+	nextChunkSize := nextPow2(chunks)
+	for nextChunkSize < paddedChunks {
+		nextChunkSize *= 2
+	}
 
 	for paddedChunks > 0 {
 		if paddedChunks >= nextChunkSize {
@@ -80,28 +96,34 @@ func (flow *Flow) splitNodes() []int64 {
 		}
 		nextChunkSize /= 2
 	}
-	logrus.WithFields(logrus.Fields{
-		"chunks":   chunks,
-		"nodeSize": nodes,
-	}).Debug("SplitNodes")
 
 	return nodes
 }
 
 func (flow *Flow) createNode(offset, chunks int64) (*contract.SubmissionNode, error) {
+	layout := flow.file.layout
+
 	batch := chunks
-	if chunks > DefaultSegmentMaxChunks {
-		batch = DefaultSegmentMaxChunks
+	if chunks > int64(layout.SegmentMaxChunks) {
+		batch = int64(layout.SegmentMaxChunks)
 	}
 
-	return flow.createSegmentNode(offset, DefaultChunkSize*batch, DefaultChunkSize*chunks)
+	return flow.createSegmentNode(offset, int64(layout.ChunkSize)*batch, int64(layout.ChunkSize)*chunks)
 }
 
 func (flow *Flow) createSegmentNode(offset, batch, size int64) (*contract.SubmissionNode, error) {
-	iter := NewIterator(flow.file.underlying, flow.file.Size(), offset, batch, true)
-	var builder merkle.TreeBuilder
+	layout := flow.file.layout
+	iter := NewIterator(flow.file.underlying, flow.file.Size(), offset, batch, true, layout)
+	builder := merkle.TreeBuilder{Hasher: layout.Hasher}
 
 	for i := int64(0); i < size; {
+		// batch of padding chunks only is not read from file
+		if offset+i >= flow.file.Size() {
+			builder.AppendHash(layout.zeroSegmentRoot(int(batch / int64(layout.ChunkSize))))
+			i += batch
+			continue
+		}
+
 		ok, err := iter.Next()
 		if err != nil {
 			return nil, err
@@ -117,11 +139,11 @@ func (flow *Flow) createSegmentNode(offset, batch, size int64) (*contract.Submis
 		}
 
 		segment := iter.Current()
-		builder.AppendHash(segmentRoot(segment))
+		builder.AppendHash(layout.segmentRoot(segment))
 		i += int64(len(segment))
 	}
 
-	numChunks := size / DefaultChunkSize
+	numChunks := size / int64(layout.ChunkSize)
 	height := int64(math.Log2(float64(numChunks)))
 
 	return &contract.SubmissionNode{
@@ -129,3 +151,135 @@ func (flow *Flow) createSegmentNode(offset, batch, size int64) (*contract.Submis
 		Height: big.NewInt(height),
 	}, nil
 }
+
+// FlowNode is a submission node located in the global flow merkle tree.
+type FlowNode struct {
+	Root     common.Hash
+	Height   uint64
+	StartPos uint64 // position of the first chunk in flow
+}
+
+// Index returns the node index at level Height of flow merkle tree.
+func (node *FlowNode) Index() uint64 {
+	return node.StartPos >> node.Height
+}
+
+// ValidateProof validates the merkle proof of node against the flow root and length in mine context.
+func (node *FlowNode) ValidateProof(context *contract.MineContext, proof *merkle.Proof) error {
+	if !context.FlowLength.IsUint64() {
+		return errors.Errorf("Invalid flow length %v", context.FlowLength)
+	}
+
+	flowLength := context.FlowLength.Uint64()
+	if node.StartPos+(1<<node.Height) > flowLength {
+		return errors.Errorf("Node [%v, %v) out of flow length %v", node.StartPos, node.StartPos+(1<<node.Height), flowLength)
+	}
+
+	// number of nodes at level Height of flow merkle tree
+	numNodes := (flowLength-1)>>node.Height + 1
+	if numNodes > math.MaxUint32 {
+		return errors.Errorf("Too many nodes %v at level %v of flow", numNodes, node.Height)
+	}
+
+	return proof.ValidateHash(context.FlowRoot, node.Root, uint32(node.Index()), uint32(numNodes))
+}
+
+// VerifySubmission verifies the on-chain submission of file is included in the flow of mine context,
+// and returns the submission nodes located in flow merkle tree.
+//
+// Submission nodes are recomputed from file and compared with the specified submission. Then,
+// nodes are placed one by one from startPos, which should be aligned with the node size, and
+// all nodes should be within the flow length of mine context. Since flow root could not be
+// recomputed from a single file, each node is validated against the flow root with the inclusion
+// proof in the same order of nodes, e.g. proofs generated by flow accumulator via
+// Accumulator.SubmissionProofs once validated by ValidateAccumulator.
+func (flow *Flow) VerifySubmission(submission *contract.Submission, startPos *big.Int, context *contract.MineContext, proofs []merkle.Proof) ([]FlowNode, error) {
+	expected, err := flow.CreateSubmission()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create flow submission")
+	}
+
+	if submission.Length.Cmp(expected.Length) != 0 {
+		return nil, errors.Errorf("Submission length mismatch, expected = %v, actual = %v", expected.Length, submission.Length)
+	}
+
+	if len(submission.Nodes) != len(expected.Nodes) {
+		return nil, errors.Errorf("Submission nodes mismatch, expected = %v, actual = %v", len(expected.Nodes), len(submission.Nodes))
+	}
+
+	if len(proofs) != len(submission.Nodes) {
+		return nil, errors.Errorf("Submission proofs mismatch, expected = %v, actual = %v", len(submission.Nodes), len(proofs))
+	}
+
+	if !startPos.IsUint64() {
+		return nil, errors.Errorf("Invalid start position %v", startPos)
+	}
+
+	var nodes []FlowNode
+	pos := startPos.Uint64()
+
+	for i, v := range submission.Nodes {
+		if v.Root != expected.Nodes[i].Root {
+			return nil, errors.Errorf("Submission node %v root mismatch, expected = %v, actual = %v",
+				i, common.Hash(expected.Nodes[i].Root), common.Hash(v.Root))
+		}
+
+		if v.Height.Cmp(expected.Nodes[i].Height) != 0 {
+			return nil, errors.Errorf("Submission node %v height mismatch, expected = %v, actual = %v",
+				i, expected.Nodes[i].Height, v.Height)
+		}
+
+		height := v.Height.Uint64()
+		if pos%(1<<height) != 0 {
+			return nil, errors.Errorf("Submission node %v at position %v not aligned with height %v", i, pos, height)
+		}
+
+		nodes = append(nodes, FlowNode{
+			Root:     v.Root,
+			Height:   height,
+			StartPos: pos,
+		})
+
+		pos += 1 << height
+	}
+
+	if !context.FlowLength.IsUint64() || context.FlowLength.Uint64() < pos {
+		return nil, errors.Errorf("Submission [%v, %v) not included in flow length %v", startPos, pos, context.FlowLength)
+	}
+
+	for i, v := range nodes {
+		if err := v.ValidateProof(context, &proofs[i]); err != nil {
+			return nil, errors.WithMessagef(err, "Submission node %v not included in flow", i)
+		}
+	}
+
+	return nodes, nil
+}
+
+// AccumulateSubmission appends nodes of the on-chain submission into flow accumulator in order,
+// and returns the start position of submission in flow.
+func AccumulateSubmission(acc *merkle.Accumulator, submission *contract.Submission) (uint64, error) {
+	var nodes []merkle.SubtreeNode
+
+	for i, v := range submission.Nodes {
+		if !v.Height.IsUint64() {
+			return 0, errors.Errorf("Invalid height %v of submission node %v", v.Height, i)
+		}
+
+		nodes = append(nodes, merkle.SubtreeNode{
+			Root:   v.Root,
+			Height: v.Height.Uint64(),
+		})
+	}
+
+	return acc.Append(nodes)
+}
+
+// ValidateAccumulator validates the flow accumulator against flow root and length of on-chain mine context.
+func ValidateAccumulator(acc *merkle.Accumulator, context *contract.MineContext) error {
+	if !context.FlowLength.IsUint64() {
+		return errors.Errorf("Invalid flow length %v", context.FlowLength)
+	}
+
+	return acc.Validate(context.FlowRoot, context.FlowLength.Uint64())
+}
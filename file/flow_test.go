@@ -0,0 +1,156 @@
+package file
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/contract"
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+// createFlowTree appends the file to a flow with the specified number of existing chunks,
+// and returns the flow tree and start position of file.
+func createFlowTree(t *testing.T, file *File, submission *contract.Submission, existing uint64) (*merkle.Tree, uint64) {
+	var builder merkle.TreeBuilder
+
+	// align the start position with the first submission node
+	alignment := uint64(1) << submission.Nodes[0].Height.Uint64()
+	startPos := (existing + alignment - 1) / alignment * alignment
+
+	for i := uint64(0); i < startPos; i++ {
+		builder.Append(createChunkData(i))
+	}
+
+	data, err := file.ReadChunks(0, file.NumPaddedChunks())
+	assert.NoError(t, err)
+
+	for _, chunk := range splitChunks(data) {
+		builder.Append(chunk)
+	}
+
+	return builder.Build(), startPos
+}
+
+func createChunkData(i uint64) []byte {
+	data := make([]byte, DefaultChunkSize)
+	data[0], data[1] = byte(i), byte(i>>8)
+	return data
+}
+
+func TestVerifySubmission(t *testing.T) {
+	for _, size := range []int{1, DefaultSegmentSize, 5*DefaultSegmentSize + 1000} {
+		file, _ := createTestFile(t, size)
+		flow := NewFlow(file)
+
+		submission, err := flow.CreateSubmission()
+		assert.NoError(t, err)
+
+		flowTree, startPos := createFlowTree(t, file, submission, 1000)
+		levels := flowTree.Levels()
+		context := contract.MineContext{
+			FlowRoot:   flowTree.Root(),
+			FlowLength: new(big.Int).SetUint64(uint64(flowTree.NumLeafNodes())),
+		}
+
+		// prove nodes with the tree made up of nodes at the same level
+		var proofs []merkle.Proof
+		pos := startPos
+		for _, v := range submission.Nodes {
+			height := v.Height.Uint64()
+
+			var builder merkle.TreeBuilder
+			for _, hash := range levels[height] {
+				builder.AppendHash(hash)
+			}
+
+			proofs = append(proofs, builder.Build().ProofAt(int(pos>>height)))
+			pos += 1 << height
+		}
+
+		nodes, err := flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos), &context, proofs)
+		assert.NoError(t, err)
+		assert.Equal(t, len(submission.Nodes), len(nodes))
+
+		for i, node := range nodes {
+			assert.Equal(t, levels[node.Height][node.Index()], node.Root)
+			assert.NoError(t, node.ValidateProof(&context, &proofs[i]))
+
+			wrongNode := node
+			wrongNode.StartPos += 1 << node.Height
+			assert.Error(t, wrongNode.ValidateProof(&context, &proofs[i]))
+		}
+
+		// proofs required
+		_, err = flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos), &context, nil)
+		assert.Error(t, err)
+
+		// not included in flow of a different root, even if nodes are well placed
+		wrongContext := context
+		wrongContext.FlowRoot[0]++
+		_, err = flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos), &wrongContext, proofs)
+		assert.Error(t, err)
+
+		// misaligned start position
+		if submission.Nodes[0].Height.Uint64() > 0 {
+			_, err = flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos+1), &context, proofs)
+			assert.Error(t, err)
+		}
+
+		// not included in flow length
+		shortContext := context
+		shortContext.FlowLength = new(big.Int).SetUint64(startPos)
+		_, err = flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos), &shortContext, proofs)
+		assert.Error(t, err)
+
+		// root mismatch
+		wrongSubmission := *submission
+		wrongSubmission.Nodes = append([]contract.SubmissionNode{}, submission.Nodes...)
+		wrongSubmission.Nodes[0].Root[0]++
+		_, err = flow.VerifySubmission(&wrongSubmission, new(big.Int).SetUint64(startPos), &context, proofs)
+		assert.Error(t, err)
+	}
+}
+
+func TestAccumulateSubmission(t *testing.T) {
+	acc := merkle.NewAccumulator()
+	var leaves [][]byte
+
+	for _, size := range []int{1000, 5*DefaultSegmentSize + 1000, DefaultSegmentSize} {
+		file, _ := createTestFile(t, size)
+		flow := NewFlow(file)
+
+		submission, err := flow.CreateSubmission()
+		assert.NoError(t, err)
+
+		startPos, err := AccumulateSubmission(acc, submission)
+		assert.NoError(t, err)
+
+		// padded with zero chunks before submission
+		for uint64(len(leaves)) < startPos {
+			leaves = append(leaves, make([]byte, DefaultChunkSize))
+		}
+
+		data, err := file.ReadChunks(0, file.NumPaddedChunks())
+		assert.NoError(t, err)
+		leaves = append(leaves, splitChunks(data)...)
+
+		var builder merkle.TreeBuilder
+		for _, v := range leaves {
+			builder.Append(v)
+		}
+
+		context := contract.MineContext{
+			FlowRoot:   builder.Build().Root(),
+			FlowLength: big.NewInt(int64(len(leaves))),
+		}
+		assert.NoError(t, ValidateAccumulator(acc, &context))
+
+		proofs, err := acc.SubmissionProofs(acc.NumSubmissions() - 1)
+		assert.NoError(t, err)
+
+		nodes, err := flow.VerifySubmission(submission, new(big.Int).SetUint64(startPos), &context, proofs)
+		assert.NoError(t, err)
+		assert.Equal(t, len(submission.Nodes), len(nodes))
+	}
+}
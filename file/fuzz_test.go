@@ -0,0 +1,131 @@
+//go:build go1.18
+// +build go1.18
+
+package file
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzFlowPadding(f *testing.F) {
+	for _, chunks := range []uint32{1, 2, 3, 16, 17, 1000, 1<<20 + 1, 1<<31 + 1, 1<<32 - 1} {
+		f.Add(chunks)
+	}
+
+	f.Fuzz(func(t *testing.T, chunks uint32) {
+		if chunks == 0 {
+			return
+		}
+
+		if expected := naiveNextPow2(chunks); nextPow2(chunks) != expected {
+			t.Fatalf("nextPow2(%v) = %v, expected %v", chunks, nextPow2(chunks), expected)
+		}
+
+		paddedChunks, _ := computePaddedSize(chunks)
+		if expected, _ := naiveComputePaddedSize(chunks); paddedChunks != expected {
+			t.Fatalf("computePaddedSize(%v) = %v, expected %v", chunks, paddedChunks, expected)
+		}
+
+		var sum int64
+		for _, v := range splitNodes(chunks, paddedChunks) {
+			if v&(v-1) != 0 || sum%v != 0 {
+				t.Fatalf("Invalid node %v at %v for chunks %v", v, sum, chunks)
+			}
+			sum += v
+		}
+
+		if sum != int64(paddedChunks) {
+			t.Fatalf("Nodes sum to %v, expected %v for chunks %v", sum, paddedChunks, chunks)
+		}
+	})
+}
+
+func FuzzSegmentTrimming(f *testing.F) {
+	f.Add(uint64(1), uint8(8), uint8(10))
+	f.Add(uint64(5<<30+12345), uint8(8), uint8(10))
+	f.Add(uint64(1000), uint8(4), uint8(2))
+
+	f.Fuzz(func(t *testing.T, size uint64, chunkBits, segmentBits uint8) {
+		layout := layoutOrDefault(Layout{ChunkSize: 1 << (chunkBits % 13), SegmentMaxChunks: 1 << (segmentBits % 13)})
+		fileSize := int64(size%(1<<40)) + 1
+
+		numChunks := numSplits(fileSize, layout.ChunkSize)
+		if uint64(numChunks) != (uint64(fileSize)+uint64(layout.ChunkSize)-1)/uint64(layout.ChunkSize) {
+			return // too many chunks for the layout
+		}
+
+		numSegments := numSplits(fileSize, layout.SegmentSize())
+		start, end := layout.segmentChunks(numSegments-1, numChunks)
+		if end != numChunks || start >= end || end-start > uint32(layout.SegmentMaxChunks) {
+			t.Fatalf("Invalid last segment [%v, %v) of %v chunks", start, end, numChunks)
+		}
+
+		data := make([]byte, int64(end-start)*int64(layout.ChunkSize))
+		if trimmed := layout.trimLastChunk(data, fileSize); int64(start)*int64(layout.ChunkSize)+int64(len(trimmed)) != fileSize {
+			t.Fatalf("Invalid trimmed size %v of last segment, file size = %v", len(trimmed), fileSize)
+		}
+	})
+}
+
+func FuzzIterator(f *testing.F) {
+	f.Add(uint16(1), uint16(0), uint8(1), true)
+	f.Add(uint16(1000), uint16(16), uint8(3), true)
+	f.Add(uint16(4096), uint16(4000), uint8(64), false)
+
+	f.Fuzz(func(t *testing.T, size, offset uint16, batchChunks uint8, flowPadding bool) {
+		layout := layoutOrDefault(Layout{ChunkSize: 16, SegmentMaxChunks: 4})
+		if size == 0 || batchChunks == 0 {
+			return
+		}
+
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i%251 + 1)
+		}
+
+		filename := filepath.Join(t.TempDir(), "data")
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		// expected content padded with zeros
+		paddedChunks := uint64(numSplits(int64(size), layout.ChunkSize))
+		if flowPadding {
+			paddedChunks, _ = naiveComputePaddedSize(uint32(paddedChunks))
+		}
+		padded := make([]byte, paddedChunks*uint64(layout.ChunkSize))
+		copy(padded, data)
+
+		batch := int(batchChunks) * layout.ChunkSize
+		iter := NewIterator(file, int64(size), int64(offset), int64(batch), flowPadding, layout)
+
+		for pos := int(offset); pos < len(padded); pos += batch {
+			ok, err := iter.Next()
+			if err != nil || !ok {
+				t.Fatalf("Failed to iterate at %v, ok = %v, err = %v", pos, ok, err)
+			}
+
+			end := pos + batch
+			if end > len(padded) {
+				end = len(padded)
+			}
+
+			if !bytes.Equal(padded[pos:end], iter.Current()) {
+				t.Fatalf("Invalid content at %v, size = %v, batch = %v", pos, size, batch)
+			}
+		}
+
+		if ok, err := iter.Next(); err != nil || ok {
+			t.Fatalf("Unexpected data after padded size, ok = %v, err = %v", ok, err)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode returns the inode number of file, or false if unavailable.
+func inode(_ *os.File, info os.FileInfo) (uint64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode returns the file index of file, which is unique on a volume as inode number, or false if
+// unavailable.
+func inode(file *os.File, _ os.FileInfo) (uint64, bool) {
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(file.Fd()), &info); err != nil {
+		return 0, false
+	}
+
+	return uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow), true
+}
@@ -14,26 +14,33 @@ type Iterator struct {
 	fileSize   int64
 	paddedSize uint64
 	offset     int64 // offset to read data
+	zeroed     int   // size of leading zeros in buffer, so as to avoid filling zeros for padding again
 }
 
-func NewSegmentIterator(file *os.File, fileSize int64, offset int64, flowPadding bool) *Iterator {
-	return NewIterator(file, fileSize, offset, DefaultChunkSize*DefaultSegmentMaxChunks, flowPadding)
+// NewSegmentIterator creates an iterator to read file segment by segment, where layout is the
+// default layout if not specified.
+func NewSegmentIterator(file *os.File, fileSize int64, offset int64, flowPadding bool, layout ...Layout) *Iterator {
+	l := layoutOrDefault(layout...)
+	return NewIterator(file, fileSize, offset, int64(l.SegmentSize()), flowPadding, l)
 }
 
-func NewIterator(file *os.File, fileSize int64, offset int64, batch int64, flowPadding bool) *Iterator {
-	if batch%DefaultChunkSize > 0 {
+// NewIterator creates an iterator to read file in batch, where layout is the default layout if not specified.
+func NewIterator(file *os.File, fileSize int64, offset int64, batch int64, flowPadding bool, layout ...Layout) *Iterator {
+	l := layoutOrDefault(layout...)
+	chunkSize := int64(l.ChunkSize)
+
+	if batch%chunkSize > 0 {
 		panic("batch size should align with chunk size")
 	}
 
 	buf := make([]byte, batch)
 
-	chunks := (fileSize-1)/DefaultChunkSize + 1
+	chunks := (fileSize-1)/chunkSize + 1
 	var paddedSize uint64
 	if flowPadding {
-		paddedChunks, _ := computePaddedSize(uint32(chunks))
-		paddedSize = paddedChunks * DefaultChunkSize
+		paddedSize = l.Padding(uint32(chunks)) * uint64(chunkSize)
 	} else {
-		paddedSize = uint64(chunks) * DefaultChunkSize
+		paddedSize = uint64(chunks) * uint64(chunkSize)
 	}
 
 	return &Iterator{
@@ -68,6 +75,7 @@ func (it *Iterator) Next() (bool, error) {
 
 	n, err := it.file.ReadAt(it.buf, it.offset)
 	it.bufSize = n
+	it.zeroed = 0
 	it.offset += int64(n)
 
 	// not reach EOF
@@ -96,9 +104,21 @@ func (it *Iterator) clearBuffer() {
 
 func (it *Iterator) paddingZeros(length int) {
 	startOffset := it.bufSize
-	for i := 0; i < length; i++ {
-		it.buf[startOffset+i] = 0
+
+	// skip zeros already filled for padding in previous batch
+	fillOffset := startOffset
+	if fillOffset < it.zeroed {
+		fillOffset = it.zeroed
+	}
+
+	for i := fillOffset; i < startOffset+length; i++ {
+		it.buf[i] = 0
 	}
+
+	if startOffset == 0 && length > it.zeroed {
+		it.zeroed = length
+	}
+
 	it.bufSize += length
 	it.offset += int64(length)
 }
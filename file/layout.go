@@ -0,0 +1,149 @@
+package file
+
+import (
+	"bytes"
+	"math/bits"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// PaddingFunc returns the number of chunks padded for flow submission.
+type PaddingFunc func(numChunks uint32) uint64
+
+// FlowPadding pads chunks to align with 1/16 of the next power of 2, so that the file could be split
+// into a few power of 2 sub trees in flow.
+func FlowPadding(numChunks uint32) uint64 {
+	paddedChunks, _ := computePaddedSize(numChunks)
+	return paddedChunks
+}
+
+// Layout defines how file is split into chunks and segments, hashed and padded for flow. Zero
+// value fields are replaced with the default layout of Ionian network, so as to test alternative
+// networks and devnets.
+type Layout struct {
+	ChunkSize        int           // size of chunk in bytes, i.e. leaf node of file merkle tree
+	SegmentMaxChunks int           // maximum number of chunks in a segment, should be power of 2
+	Hasher           merkle.Hasher // hasher of merkle tree
+	Padding          PaddingFunc   // padding rule of flow submission
+
+	// Directory to cache segment roots and root of file merkle tree, so as to avoid rehashing large
+	// files. Cache is disabled if empty. Note, it is not a part of file format.
+	MerkleCacheDir string
+
+	zeros *merkle.ZeroHashes
+}
+
+// DefaultLayout returns the layout of Ionian network.
+func DefaultLayout() Layout {
+	return Layout{
+		ChunkSize:        DefaultChunkSize,
+		SegmentMaxChunks: DefaultSegmentMaxChunks,
+		Hasher:           merkle.DefaultHasher,
+		Padding:          FlowPadding,
+	}
+}
+
+// layoutOrDefault returns the layout with zero value fields replaced by the default layout. Invalid
+// values, e.g. negative chunk size, are kept as they are so as to be rejected by Validate.
+func layoutOrDefault(layout ...Layout) Layout {
+	var l Layout
+	if len(layout) > 0 {
+		l = layout[0]
+	}
+
+	if l.ChunkSize == 0 {
+		l.ChunkSize = DefaultChunkSize
+	}
+
+	if l.SegmentMaxChunks == 0 {
+		l.SegmentMaxChunks = DefaultSegmentMaxChunks
+	}
+
+	if l.Hasher == nil {
+		l.Hasher = merkle.DefaultHasher
+	}
+
+	if l.Padding == nil {
+		l.Padding = FlowPadding
+	}
+
+	// zero hashes are always derived from hasher, and invalid chunk size is rejected by Validate
+	if l.ChunkSize > 0 {
+		l.zeros = merkle.ZeroHashesOf(l.Hasher, l.ChunkSize)
+	}
+
+	return l
+}
+
+// Validate validates the layout.
+func (layout *Layout) Validate() error {
+	if layout.ChunkSize <= 0 {
+		return errors.Errorf("Invalid chunk size %v", layout.ChunkSize)
+	}
+
+	if layout.SegmentMaxChunks <= 0 || layout.SegmentMaxChunks&(layout.SegmentMaxChunks-1) != 0 {
+		return errors.Errorf("Segment max chunks %v should be power of 2", layout.SegmentMaxChunks)
+	}
+
+	return nil
+}
+
+// SegmentSize returns the maximum size of segment in bytes.
+func (layout *Layout) SegmentSize() int {
+	return layout.ChunkSize * layout.SegmentMaxChunks
+}
+
+// segmentLevels returns the number of levels from chunk to segment root in file merkle tree.
+func (layout *Layout) segmentLevels() int {
+	return bits.TrailingZeros(uint(layout.SegmentMaxChunks))
+}
+
+// segmentRoot returns the merkle root of chunks, where trailing all-zero chunks, e.g. padding, are
+// not hashed but replaced with precomputed zero subtree hashes.
+func (layout *Layout) segmentRoot(chunks []byte) common.Hash {
+	dataLen := len(chunks)
+	if dataLen == 0 {
+		return common.Hash{}
+	}
+
+	numChunks := dataLen / layout.ChunkSize
+	numDataChunks := (len(bytes.TrimRight(chunks, "\x00")) + layout.ChunkSize - 1) / layout.ChunkSize
+
+	leafHashes := make([]common.Hash, numDataChunks)
+	for i := range leafHashes {
+		leafHashes[i] = layout.Hasher.LeafHash(chunks[i*layout.ChunkSize : (i+1)*layout.ChunkSize])
+	}
+
+	return layout.zeros.PaddedRoot(leafHashes, numChunks)
+}
+
+// zeroSegmentRoot returns the merkle root of a segment of numChunks padding chunks, which is
+// computed from precomputed zero subtree hashes without reading or hashing zeros.
+func (layout *Layout) zeroSegmentRoot(numChunks int) common.Hash {
+	return layout.zeros.PaddedRoot(nil, numChunks)
+}
+
+// segmentChunks returns the chunk range [start, end) of the specified segment in a file of numChunks
+// chunks, where the last segment may have less chunks.
+func (layout *Layout) segmentChunks(segment, numChunks uint32) (uint32, uint32) {
+	start := segment * uint32(layout.SegmentMaxChunks)
+	end := start + uint32(layout.SegmentMaxChunks)
+	if end > numChunks {
+		end = numChunks
+	}
+
+	return start, end
+}
+
+// trimLastChunk removes the padding zeros of the last chunk from data that ends with the last chunk
+// of a file in fileSize bytes.
+func (layout *Layout) trimLastChunk(data []byte, fileSize int64) []byte {
+	chunkSize := int64(layout.ChunkSize)
+	if lastChunkSize := fileSize % chunkSize; lastChunkSize > 0 {
+		return data[:len(data)-int(chunkSize-lastChunkSize)]
+	}
+
+	return data
+}
@@ -0,0 +1,137 @@
+package file
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256Hasher is an alternative hasher without domain prefixes.
+type sha256Hasher struct{}
+
+func (sha256Hasher) LeafHash(content []byte) common.Hash {
+	return sha256.Sum256(content)
+}
+
+func (sha256Hasher) InteriorHash(left, right common.Hash) common.Hash {
+	return sha256.Sum256(append(left.Bytes(), right.Bytes()...))
+}
+
+func createTestFileWithLayout(t *testing.T, size int, layout Layout) (*File, []byte) {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	filename := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(filename, data, 0644))
+
+	file, err := Open(filename, layout)
+	assert.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	return file, data
+}
+
+func TestLayoutValidate(t *testing.T) {
+	layout := DefaultLayout()
+	assert.NoError(t, layout.Validate())
+	assert.Equal(t, DefaultSegmentSize, layout.SegmentSize())
+
+	layout.SegmentMaxChunks = 12
+	assert.Error(t, layout.Validate())
+
+	_, err := Open(filepath.Join(t.TempDir(), "data"), Layout{SegmentMaxChunks: 12})
+	assert.Error(t, err)
+
+	// only zero values are replaced with the default layout
+	for _, v := range []Layout{{ChunkSize: -1}, {SegmentMaxChunks: -1024}} {
+		layout = layoutOrDefault(v)
+		assert.Error(t, layout.Validate())
+	}
+
+	layout = layoutOrDefault(Layout{})
+	assert.NoError(t, layout.Validate())
+	assert.Equal(t, DefaultSegmentSize, layout.SegmentSize())
+}
+
+func TestCustomLayout(t *testing.T) {
+	layout := Layout{ChunkSize: 128, SegmentMaxChunks: 16, Hasher: sha256Hasher{}}
+
+	for _, size := range []int{1, 128, 16 * 128, 5*16*128 + 300} {
+		file, data := createTestFileWithLayout(t, size, layout)
+		assert.Equal(t, uint32((size+127)/128), file.NumChunks())
+		assert.Equal(t, uint32((size+16*128-1)/(16*128)), file.NumSegments())
+
+		tree, err := file.MerkleTree()
+		assert.NoError(t, err)
+
+		// build tree of all padded chunks naively
+		builder := merkle.TreeBuilder{Hasher: sha256Hasher{}}
+		padded := make([]byte, int(file.NumPaddedChunks())*128)
+		copy(padded, data)
+		for offset := 0; offset < len(padded); offset += 128 {
+			builder.Append(padded[offset : offset+128])
+		}
+		assert.Equal(t, builder.Build().Root(), tree.Root(), "size = %v", size)
+
+		// the default layout results in a different root
+		defaultFile, _ := createTestFile(t, size)
+		defaultTree, err := defaultFile.MerkleTree()
+		assert.NoError(t, err)
+		assert.NotEqual(t, defaultTree.Root(), tree.Root())
+
+		for _, proofType := range []string{ProofTypeChunk, ProofTypeSegment} {
+			proof, content, err := file.ContentProof(tree, proofType, 0)
+			assert.NoError(t, err)
+			assert.NoError(t, proof.Validate(tree.Root(), content, layout), "size = %v, type = %v", size, proofType)
+			assert.Error(t, proof.Validate(tree.Root(), content))
+			assert.NoError(t, proof.ValidatePosition(proofType, 0, int64(size), layout))
+		}
+
+		// submission nodes are hashed with the layout as well
+		submission, err := NewFlow(file).CreateSubmission()
+		assert.NoError(t, err)
+
+		var numChunks uint64
+		for _, node := range submission.Nodes {
+			numChunks += uint64(1) << node.Height.Uint64()
+		}
+		assert.Equal(t, file.Layout().Padding(file.NumChunks()), numChunks)
+	}
+}
+
+func TestLayoutLargePadding(t *testing.T) {
+	// padding segments should not be read or hashed, otherwise 4 GB zeros are hashed
+	const paddedChunks = 1 << 24
+	layout := Layout{Padding: func(uint32) uint64 { return paddedChunks }}
+	file, data := createTestFileWithLayout(t, DefaultSegmentSize+1000, layout)
+
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, paddedChunks/DefaultSegmentMaxChunks, tree.NumLeafNodes())
+
+	// build tree with segment roots, where the second segment is partially padded
+	padded := make([]byte, 2*DefaultSegmentSize)
+	copy(padded, data)
+
+	l := layoutOrDefault()
+	builder := merkle.TreeBuilder{}
+	builder.AppendHash(l.segmentRoot(padded[:DefaultSegmentSize]))
+	builder.AppendHash(l.segmentRoot(padded[DefaultSegmentSize:]))
+	for i := 2; i < paddedChunks/DefaultSegmentMaxChunks; i++ {
+		builder.AppendHash(l.zeros.Hash(l.segmentLevels()))
+	}
+	assert.Equal(t, builder.Build().Root(), tree.Root())
+
+	// submission of a single node with the same root
+	submission, err := NewFlow(file).CreateSubmission()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(submission.Nodes))
+	assert.Equal(t, tree.Root(), common.Hash(submission.Nodes[0].Root))
+	assert.Equal(t, uint64(24), submission.Nodes[0].Height.Uint64())
+}
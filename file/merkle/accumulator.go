@@ -0,0 +1,386 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxAccumulatorLength is the maximum number of leaf nodes in accumulator.
+const maxAccumulatorLength = uint64(1) << 62
+
+var (
+	errAccumulatorEmpty          = errors.New("accumulator is empty")
+	errAccumulatorEncoding       = errors.New("invalid accumulator encoding")
+	errSubtreeTooHigh            = errors.New("subtree too high")
+	errAccumulatorLengthOverflow = errors.New("accumulator length overflow")
+	errAccumulatorNodeNotFound   = errors.New("node not found in accumulator")
+)
+
+// SubtreeNode is the root of a complete subtree, e.g. node of flow submission.
+type SubtreeNode struct {
+	Root   common.Hash
+	Height uint64 // subtree with 2^Height leaf nodes
+}
+
+// AccumulatedNode is a subtree node appended into accumulator.
+type AccumulatedNode struct {
+	SubtreeNode
+	StartPos uint64 // position of the first leaf node in accumulator
+}
+
+// Index returns the node index at level Height of accumulator.
+func (node *AccumulatedNode) Index() uint64 {
+	return node.StartPos >> node.Height
+}
+
+// Accumulator is an append-only merkle tree that ingests complete subtrees in order, e.g. the global
+// flow merkle tree made up of submission nodes. Before appending a subtree, the accumulator is
+// padded with all-zero leaf nodes so that subtree is aligned with its size.
+//
+// Only roots of appended subtrees and their ancestors are stored, so memory is proportional to the
+// number of appended subtrees rather than leaf nodes.
+type Accumulator struct {
+	zeros       *ZeroHashes              // hasher and padding
+	length      uint64                   // number of leaf nodes, including padding
+	nodes       []map[uint64]common.Hash // complete nodes at each level by index
+	submissions [][]AccumulatedNode      // appended subtrees grouped by submission
+}
+
+// NewAccumulator creates an empty accumulator, which is padded with zeros of DefaultHasher and
+// DefaultZeroLeafSize if not specified.
+func NewAccumulator(zeros ...*ZeroHashes) *Accumulator {
+	if len(zeros) > 0 && zeros[0] != nil {
+		return &Accumulator{zeros: zeros[0]}
+	}
+
+	return &Accumulator{zeros: defaultZeroHashes}
+}
+
+// Length returns the number of leaf nodes, including padding.
+func (acc *Accumulator) Length() uint64 {
+	return acc.length
+}
+
+// NumSubmissions returns the number of appended submissions.
+func (acc *Accumulator) NumSubmissions() int {
+	return len(acc.submissions)
+}
+
+// Submission returns the appended nodes of the specified submission.
+func (acc *Accumulator) Submission(i int) []AccumulatedNode {
+	return acc.submissions[i]
+}
+
+// Append appends all nodes of a submission in order, and returns the start position of the first node.
+func (acc *Accumulator) Append(nodes []SubtreeNode) (uint64, error) {
+	var accumulated []AccumulatedNode
+	length := acc.length
+
+	// validate before any change
+	for _, v := range nodes {
+		if v.Height >= 64 || uint64(1)<<v.Height > maxAccumulatorLength {
+			return 0, errSubtreeTooHigh
+		}
+
+		start := alignUp(length, v.Height)
+		end := start + 1<<v.Height
+		if end > maxAccumulatorLength {
+			return 0, errAccumulatorLengthOverflow
+		}
+
+		accumulated = append(accumulated, AccumulatedNode{v, start})
+		length = end
+	}
+
+	for _, v := range accumulated {
+		acc.pad(v.StartPos)
+		acc.insert(v.Root, v.Height)
+	}
+
+	acc.submissions = append(acc.submissions, accumulated)
+
+	if len(accumulated) == 0 {
+		return acc.length, nil
+	}
+
+	return accumulated[0].StartPos, nil
+}
+
+func alignUp(pos, height uint64) uint64 {
+	mask := uint64(1)<<height - 1
+	return (pos + mask) &^ mask
+}
+
+// pad appends zero subtrees until length reaches the specified position.
+func (acc *Accumulator) pad(pos uint64) {
+	for acc.length < pos {
+		// the highest subtree that aligned with current length and not exceeds pos
+		height := uint64(bits.Len64(pos-acc.length) - 1)
+		if acc.length > 0 {
+			if aligned := uint64(bits.TrailingZeros64(acc.length)); aligned < height {
+				height = aligned
+			}
+		}
+
+		acc.insert(acc.zeros.Hash(int(height)), height)
+	}
+}
+
+// insert inserts an aligned complete subtree, and computes the parent nodes once completed.
+func (acc *Accumulator) insert(root common.Hash, height uint64) {
+	index := acc.length >> height
+	acc.setNode(height, index, root)
+	acc.length += 1 << height
+
+	for ; index%2 == 1; index /= 2 {
+		left := acc.nodes[height][index-1]
+		root = acc.zeros.hasher.InteriorHash(left, root)
+		height++
+		acc.setNode(height, index/2, root)
+	}
+}
+
+func (acc *Accumulator) setNode(height, index uint64, hash common.Hash) {
+	for uint64(len(acc.nodes)) <= height {
+		acc.nodes = append(acc.nodes, make(map[uint64]common.Hash))
+	}
+
+	acc.nodes[height][index] = hash
+}
+
+// numNodes returns the number of nodes at the specified level.
+func (acc *Accumulator) numNodes(height uint64) uint64 {
+	return (acc.length-1)>>height + 1
+}
+
+// nodeHash returns the hash of node at the specified level, where the last single node at each level
+// is promoted to the upper level without hashing. Returns error if the node is inside an appended
+// subtree, whose descendants are not stored.
+func (acc *Accumulator) nodeHash(height, index uint64) (common.Hash, error) {
+	start := index << height
+
+	// complete node
+	if acc.length-start >= 1<<height {
+		if height < uint64(len(acc.nodes)) {
+			if hash, ok := acc.nodes[height][index]; ok {
+				return hash, nil
+			}
+		}
+
+		return common.Hash{}, fmt.Errorf("%w, height = %v, index = %v", errAccumulatorNodeNotFound, height, index)
+	}
+
+	left, err := acc.nodeHash(height-1, index*2)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// promoted from the left child
+	if acc.length-start <= 1<<(height-1) {
+		return left, nil
+	}
+
+	right, err := acc.nodeHash(height-1, index*2+1)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return acc.zeros.hasher.InteriorHash(left, right), nil
+}
+
+// topHeight returns the height of root node.
+func (acc *Accumulator) topHeight() uint64 {
+	return uint64(bits.Len64(acc.length - 1))
+}
+
+// Root returns the merkle root of accumulator.
+func (acc *Accumulator) Root() (common.Hash, error) {
+	if acc.length == 0 {
+		return common.Hash{}, errAccumulatorEmpty
+	}
+
+	return acc.nodeHash(acc.topHeight(), 0)
+}
+
+// Validate validates the accumulator against the specified root and length, e.g. flow root and length
+// of on-chain mine context.
+func (acc *Accumulator) Validate(root common.Hash, length uint64) error {
+	if acc.length != length {
+		return fmt.Errorf("accumulator length mismatch, expected = %v, actual = %v", length, acc.length)
+	}
+
+	actual, err := acc.Root()
+	if err != nil {
+		return err
+	}
+
+	if actual != root {
+		return fmt.Errorf("accumulator root mismatch, expected = %v, actual = %v", root, actual)
+	}
+
+	return nil
+}
+
+// ProofAt returns the inclusion proof of appended node, which could be validated by
+// Proof.ValidateHash(root, node.Root, node.Index(), numLeafNodes), where numLeafNodes is the
+// number of nodes at level node.Height. Returns error if node is not aligned, out of bound or
+// inside an appended subtree.
+func (acc *Accumulator) ProofAt(node AccumulatedNode) (Proof, error) {
+	if node.Height >= 64 || node.StartPos%(1<<node.Height) != 0 || node.StartPos >= acc.length ||
+		acc.length-node.StartPos < 1<<node.Height {
+		return Proof{}, errors.New("node out of bound")
+	}
+
+	index := node.Index()
+	hash, err := acc.nodeHash(node.Height, index)
+	if err != nil {
+		return Proof{}, err
+	}
+
+	if hash != node.Root {
+		return Proof{}, errors.New("node root mismatch")
+	}
+
+	root, err := acc.Root()
+	if err != nil {
+		return Proof{}, err
+	}
+
+	if acc.numNodes(node.Height) == 1 {
+		return Proof{Lemma: []common.Hash{root}, Path: []bool{}}, nil
+	}
+
+	proof := Proof{Lemma: []common.Hash{node.Root}}
+
+	for height := node.Height; height < acc.topHeight(); height, index = height+1, index/2 {
+		var sibling uint64
+		if index%2 == 1 {
+			sibling = index - 1
+		} else if index+1 < acc.numNodes(height) {
+			sibling = index + 1
+		} else {
+			continue
+		}
+
+		hash, err := acc.nodeHash(height, sibling)
+		if err != nil {
+			return Proof{}, err
+		}
+
+		proof.Lemma = append(proof.Lemma, hash)
+		proof.Path = append(proof.Path, index%2 == 0)
+	}
+
+	proof.Lemma = append(proof.Lemma, root)
+
+	return proof, nil
+}
+
+// SubmissionProofs returns the inclusion proofs of all nodes of the specified submission.
+func (acc *Accumulator) SubmissionProofs(i int) ([]Proof, error) {
+	var proofs []Proof
+
+	for _, v := range acc.submissions[i] {
+		proof, err := acc.ProofAt(v)
+		if err != nil {
+			return nil, err
+		}
+
+		proofs = append(proofs, proof)
+	}
+
+	return proofs, nil
+}
+
+// MarshalBinary encodes all appended submissions in order, so that accumulator could be
+// recovered by replaying submissions:
+//
+//	[numNodes: 4 bytes][[root: 32 bytes][height: 1 byte] ...] ...
+func (acc *Accumulator) MarshalBinary() ([]byte, error) {
+	var data []byte
+
+	for _, submission := range acc.submissions {
+		var numNodes [4]byte
+		binary.BigEndian.PutUint32(numNodes[:], uint32(len(submission)))
+		data = append(data, numNodes[:]...)
+
+		for _, v := range submission {
+			data = append(data, v.Root.Bytes()...)
+			data = append(data, byte(v.Height))
+		}
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary recovers accumulator from data encoded by MarshalBinary.
+func (acc *Accumulator) UnmarshalBinary(data []byte) error {
+	*acc = *NewAccumulator(acc.zeros)
+
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return errAccumulatorEncoding
+		}
+
+		numNodes := binary.BigEndian.Uint32(data)
+		data = data[4:]
+
+		if uint64(len(data)) < uint64(numNodes)*(common.HashLength+1) {
+			return errAccumulatorEncoding
+		}
+
+		nodes := make([]SubtreeNode, numNodes)
+		for i := range nodes {
+			nodes[i].Root = common.BytesToHash(data[:common.HashLength])
+			nodes[i].Height = uint64(data[common.HashLength])
+			data = data[common.HashLength+1:]
+		}
+
+		if _, err := acc.Append(nodes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Save persists the accumulator into the specified file atomically.
+func (acc *Accumulator) Save(filename string) error {
+	data, err := acc.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tmpFilename := filename + ".tmp"
+	if err = ioutil.WriteFile(tmpFilename, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFilename, filename)
+}
+
+// LoadAccumulator loads the accumulator persisted by Save. Returns an empty accumulator if file not exists.
+func LoadAccumulator(filename string, zeros ...*ZeroHashes) (*Accumulator, error) {
+	acc := NewAccumulator(zeros...)
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return acc, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = acc.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
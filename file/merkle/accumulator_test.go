@@ -0,0 +1,182 @@
+package merkle
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFlow appends submissions into accumulator, and maintains all leaf nodes for reference.
+type testFlow struct {
+	acc    *Accumulator
+	leaves [][]byte
+}
+
+func (flow *testFlow) append(t *testing.T, heights ...uint64) {
+	var nodes []SubtreeNode
+	var expectedStart uint64 = uint64(len(flow.leaves))
+
+	for i, height := range heights {
+		for uint64(len(flow.leaves))%(1<<height) != 0 {
+			flow.leaves = append(flow.leaves, make([]byte, DefaultZeroLeafSize))
+		}
+
+		if i == 0 {
+			expectedStart = uint64(len(flow.leaves))
+		}
+
+		var builder TreeBuilder
+		for j := 0; j < 1<<height; j++ {
+			chunk := createChunkData(len(flow.leaves))
+			flow.leaves = append(flow.leaves, chunk)
+			builder.Append(chunk)
+		}
+
+		nodes = append(nodes, SubtreeNode{builder.Build().Root(), height})
+	}
+
+	start, err := flow.acc.Append(nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedStart, start)
+	assert.Equal(t, uint64(len(flow.leaves)), flow.acc.Length())
+}
+
+func (flow *testFlow) validate(t *testing.T) {
+	var builder TreeBuilder
+	for _, v := range flow.leaves {
+		builder.Append(v)
+	}
+
+	tree := builder.Build()
+	assert.NoError(t, flow.acc.Validate(tree.Root(), uint64(tree.NumLeafNodes())))
+	assert.Error(t, flow.acc.Validate(tree.Root(), uint64(tree.NumLeafNodes())+1))
+
+	for i := 0; i < flow.acc.NumSubmissions(); i++ {
+		proofs, err := flow.acc.SubmissionProofs(i)
+		assert.NoError(t, err)
+
+		for j, node := range flow.acc.Submission(i) {
+			numNodes := (uint64(tree.NumLeafNodes())-1)>>node.Height + 1
+			assert.NoError(t, proofs[j].ValidateHash(tree.Root(), node.Root, uint32(node.Index()), uint32(numNodes)))
+		}
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	flow := testFlow{acc: NewAccumulator()}
+
+	_, err := flow.acc.Root()
+	assert.Error(t, err)
+
+	flow.append(t, 0)
+	flow.validate(t)
+
+	// padding required
+	flow.append(t, 3, 1)
+	flow.validate(t)
+
+	flow.append(t, 2, 0)
+	flow.append(t, 4)
+	flow.validate(t)
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		var heights []uint64
+		for h := uint64(random.Intn(6)); h > 0; h-- {
+			if random.Intn(2) == 0 {
+				heights = append(heights, h)
+			}
+		}
+
+		if len(heights) == 0 {
+			heights = append(heights, 0)
+		}
+
+		flow.append(t, heights...)
+		flow.validate(t)
+	}
+}
+
+func TestAccumulatorPersistence(t *testing.T) {
+	flow := testFlow{acc: NewAccumulator()}
+	flow.append(t, 2)
+	flow.append(t, 5, 3, 0)
+	flow.append(t, 1)
+
+	filename := filepath.Join(t.TempDir(), "flow")
+
+	acc, err := LoadAccumulator(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), acc.Length())
+
+	assert.NoError(t, flow.acc.Save(filename))
+
+	acc, err = LoadAccumulator(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, flow.acc, acc)
+
+	// continue to append after loaded
+	flow.acc = acc
+	flow.append(t, 4, 2)
+	flow.validate(t)
+
+	var corrupted Accumulator
+	data, err := acc.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Error(t, corrupted.UnmarshalBinary(data[:len(data)-1]))
+}
+
+func TestAccumulatorInvalidNode(t *testing.T) {
+	acc := NewAccumulator()
+
+	_, err := acc.Append([]SubtreeNode{{Height: 64}})
+	assert.Error(t, err)
+
+	_, err = acc.Append([]SubtreeNode{{Height: 10}, {Height: 62}})
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), acc.Length())
+	assert.Equal(t, 0, acc.NumSubmissions())
+
+	_, err = acc.Append([]SubtreeNode{{Root: common.HexToHash("0x01"), Height: 1}})
+	assert.NoError(t, err)
+
+	_, err = acc.ProofAt(AccumulatedNode{SubtreeNode{Height: 1}, 0})
+	assert.Error(t, err)
+}
+
+func TestAccumulatorProofNotAppended(t *testing.T) {
+	flow := testFlow{acc: NewAccumulator()}
+	flow.append(t, 3)
+	flow.append(t, 0, 2)
+
+	// nodes inside appended subtrees
+	for _, node := range []AccumulatedNode{
+		{SubtreeNode{Height: 0}, 2},
+		{SubtreeNode{Height: 1}, 4},
+		{SubtreeNode{Height: 0}, 13},
+	} {
+		assert.NotPanics(t, func() {
+			_, err := flow.acc.ProofAt(node)
+			assert.ErrorIs(t, err, errAccumulatorNodeNotFound, "node = %v", node)
+		})
+	}
+
+	// misaligned, out of bound or too high
+	for _, node := range []AccumulatedNode{
+		{SubtreeNode{Height: 1}, 3},
+		{SubtreeNode{Height: 2}, 16},
+		{SubtreeNode{Height: 5}, 0},
+		{SubtreeNode{Height: 64}, 0},
+	} {
+		assert.NotPanics(t, func() {
+			_, err := flow.acc.ProofAt(node)
+			assert.Error(t, err, "node = %v", node)
+		})
+	}
+
+	// appended nodes are still provable
+	flow.validate(t)
+}
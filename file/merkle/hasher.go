@@ -0,0 +1,41 @@
+package merkle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// To prevent second preimage attack
+	prefixLeaf     = byte(0)
+	prefixInterior = byte(1)
+)
+
+// Hasher computes hashes of leaf and interior nodes of merkle tree.
+type Hasher interface {
+	LeafHash(content []byte) common.Hash
+	InteriorHash(left, right common.Hash) common.Hash
+}
+
+// Keccak256Hasher hashes leaf and interior nodes with Keccak256, which are prefixed with 0 and 1
+// respectively.
+type Keccak256Hasher struct{}
+
+func (Keccak256Hasher) LeafHash(content []byte) common.Hash {
+	return crypto.Keccak256Hash([]byte{prefixLeaf}, content)
+}
+
+func (Keccak256Hasher) InteriorHash(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte{prefixInterior}, left.Bytes(), right.Bytes())
+}
+
+// DefaultHasher is the hasher used by Ionian storage node.
+var DefaultHasher Hasher = Keccak256Hasher{}
+
+func hasherOrDefault(hasher ...Hasher) Hasher {
+	if len(hasher) > 0 && hasher[0] != nil {
+		return hasher[0]
+	}
+
+	return DefaultHasher
+}
@@ -0,0 +1,50 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256Hasher is an alternative hasher without domain prefixes.
+type sha256Hasher struct{}
+
+func (sha256Hasher) LeafHash(content []byte) common.Hash {
+	return sha256.Sum256(content)
+}
+
+func (sha256Hasher) InteriorHash(left, right common.Hash) common.Hash {
+	return sha256.Sum256(append(left.Bytes(), right.Bytes()...))
+}
+
+func TestCustomHasher(t *testing.T) {
+	hasher := sha256Hasher{}
+
+	for numChunks := 1; numChunks <= 20; numChunks++ {
+		builder := TreeBuilder{Hasher: hasher}
+		var leafHashes []common.Hash
+
+		for i := 0; i < numChunks; i++ {
+			builder.Append(createChunkData(i))
+			leafHashes = append(leafHashes, hasher.LeafHash(createChunkData(i)))
+		}
+
+		tree := builder.Build()
+		assert.NotEqual(t, createTreeByChunks(numChunks).Root(), tree.Root())
+		assert.Equal(t, tree.Root(), NewZeroHashes(hasher, DefaultZeroLeafSize).PaddedRoot(leafHashes, numChunks))
+
+		for i := 0; i < numChunks; i++ {
+			proof := tree.ProofAt(i)
+			assert.NoError(t, proof.Validate(tree.Root(), createChunkData(i), uint32(i), uint32(numChunks), hasher))
+
+			if numChunks > 1 {
+				assert.Error(t, proof.Validate(tree.Root(), createChunkData(i), uint32(i), uint32(numChunks)))
+			}
+
+			rangeProof := tree.RangeProofAt(i, numChunks)
+			assert.NoError(t, rangeProof.ValidateHashes(tree.Root(), leafHashes[i:], uint32(i), uint32(numChunks), hasher))
+		}
+	}
+}
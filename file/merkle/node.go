@@ -2,13 +2,6 @@ package merkle
 
 import (
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-)
-
-const (
-	// To prevent second preimage attack
-	prefixLeaf     = byte(0)
-	prefixInterior = byte(1)
 )
 
 // Binary merkle tree node
@@ -25,17 +18,17 @@ func newNode(hash common.Hash) *node {
 	}
 }
 
-func newLeafNode(content []byte) *node {
+func newLeafNode(hasher Hasher, content []byte) *node {
 	return &node{
-		hash: crypto.Keccak256Hash([]byte{prefixLeaf}, content),
+		hash: hasher.LeafHash(content),
 	}
 }
 
-func newInteriorNode(left, right *node) *node {
+func newInteriorNode(hasher Hasher, left, right *node) *node {
 	node := &node{
 		left:  left,
 		right: right,
-		hash:  crypto.Keccak256Hash([]byte{prefixInterior}, left.hash.Bytes(), right.hash.Bytes()),
+		hash:  hasher.InteriorHash(left.hash, right.hash),
 	}
 
 	left.parent = node
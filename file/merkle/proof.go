@@ -5,7 +5,6 @@ import (
 	"math"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 var (
@@ -47,12 +46,14 @@ func (proof *Proof) validateFormat() error {
 	return nil
 }
 
-func (proof *Proof) Validate(root common.Hash, content []byte, position uint32, numLeafNodes uint32) error {
-	contentHash := crypto.Keccak256Hash([]byte{prefixLeaf}, content)
-	return proof.ValidateHash(root, contentHash, position, numLeafNodes)
+// Validate validates the proof of content at position, where hasher is DefaultHasher if not specified.
+func (proof *Proof) Validate(root common.Hash, content []byte, position uint32, numLeafNodes uint32, hasher ...Hasher) error {
+	contentHash := hasherOrDefault(hasher...).LeafHash(content)
+	return proof.ValidateHash(root, contentHash, position, numLeafNodes, hasher...)
 }
 
-func (proof *Proof) ValidateHash(root common.Hash, contentHash common.Hash, position uint32, numLeafNodes uint32) error {
+// ValidateHash validates the proof of content hash at position, where hasher is DefaultHasher if not specified.
+func (proof *Proof) ValidateHash(root common.Hash, contentHash common.Hash, position uint32, numLeafNodes uint32, hasher ...Hasher) error {
 	if err := proof.validateFormat(); err != nil {
 		return err
 	}
@@ -62,8 +63,8 @@ func (proof *Proof) ValidateHash(root common.Hash, contentHash common.Hash, posi
 		return errProofContentMismatch
 	}
 
-	// root mismatch
-	if len(proof.Lemma) > 1 && root.Hex() != proof.Lemma[len(proof.Lemma)-1].Hex() {
+	// root mismatch, and content hash is the root for single node tree
+	if root.Hex() != proof.Lemma[len(proof.Lemma)-1].Hex() {
 		return errProofRootMismatch
 	}
 
@@ -73,7 +74,7 @@ func (proof *Proof) ValidateHash(root common.Hash, contentHash common.Hash, posi
 	}
 
 	// validate root by proof
-	if !proof.validateRoot() {
+	if !proof.validateRoot(hasherOrDefault(hasher...)) {
 		return errProofValidationFailure
 	}
 
@@ -98,14 +99,14 @@ func (proof *Proof) calculateProofPosition(numLeafNodes uint32) uint32 {
 	return position
 }
 
-func (proof *Proof) validateRoot() bool {
+func (proof *Proof) validateRoot(hasher Hasher) bool {
 	hash := proof.Lemma[0]
 
 	for i, isLeft := range proof.Path {
 		if isLeft {
-			hash = crypto.Keccak256Hash([]byte{prefixInterior}, hash.Bytes(), proof.Lemma[i+1].Bytes())
+			hash = hasher.InteriorHash(hash, proof.Lemma[i+1])
 		} else {
-			hash = crypto.Keccak256Hash([]byte{prefixInterior}, proof.Lemma[i+1].Bytes(), hash.Bytes())
+			hash = hasher.InteriorHash(proof.Lemma[i+1], hash)
 		}
 	}
 
@@ -0,0 +1,282 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxProofDepth is the maximum number of sibling nodes in proof that could be encoded,
+// so that path could be packed into a uint64 bitfield.
+const MaxProofDepth = 64
+
+var (
+	errProofTooDeep         = errors.New("merkle proof too deep to encode")
+	errProofEncodingInvalid = errors.New("invalid merkle proof encoding")
+)
+
+// proofABIArguments is the ABI of (bytes32[] lemma, uint256 path), which are the proof arguments
+// of on-chain merkle proof verification, where path is the bitfield returned by PathBits.
+var proofABIArguments = func() abi.Arguments {
+	lemmaType, err := abi.NewType("bytes32[]", "", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	pathType, err := abi.NewType("uint256", "", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return abi.Arguments{{Name: "lemma", Type: lemmaType}, {Name: "path", Type: pathType}}
+}()
+
+// PathBits packs path into a bitfield, where bit i is set if Path[i] is true.
+func (proof *Proof) PathBits() uint64 {
+	var bits uint64
+
+	for i, isLeft := range proof.Path {
+		if isLeft {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits
+}
+
+func unpackPath(bits uint64, depth int) ([]bool, error) {
+	if depth < MaxProofDepth && bits>>uint(depth) != 0 {
+		return nil, errProofEncodingInvalid
+	}
+
+	path := make([]bool, depth)
+	for i := range path {
+		path[i] = bits&(1<<uint(i)) != 0
+	}
+
+	return path, nil
+}
+
+func (proof *Proof) validateEncoding() error {
+	if err := proof.validateFormat(); err != nil {
+		return err
+	}
+
+	if len(proof.Path) > MaxProofDepth {
+		return errProofTooDeep
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes proof in compact binary format:
+//
+//	[depth: 1 byte][path bitfield: (depth+7)/8 bytes][lemma: 32 bytes each]
+//
+// The bitfield is in little endian, i.e. Path[i] is bit i%8 of byte i/8, and the lemma
+// contains depth+2 hashes, or only the root hash if depth is 0.
+//
+// Note, this format is defined by the client to store proofs cheaply, and ABIEncode should be
+// used to pass proofs in contract calls.
+func (proof *Proof) MarshalBinary() ([]byte, error) {
+	if err := proof.validateEncoding(); err != nil {
+		return nil, err
+	}
+
+	depth := len(proof.Path)
+	pathSize := (depth + 7) / 8
+
+	data := make([]byte, 0, 1+pathSize+len(proof.Lemma)*common.HashLength)
+	data = append(data, byte(depth))
+
+	bits := proof.PathBits()
+	for i := 0; i < pathSize; i++ {
+		data = append(data, byte(bits>>uint(i*8)))
+	}
+
+	for _, hash := range proof.Lemma {
+		data = append(data, hash.Bytes()...)
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes proof from data encoded by MarshalBinary. Non canonical encoding,
+// e.g. trailing bytes or unused path bits set, is rejected.
+func (proof *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errProofEncodingInvalid
+	}
+
+	depth := int(data[0])
+	if depth > MaxProofDepth {
+		return errProofTooDeep
+	}
+
+	pathSize := (depth + 7) / 8
+	numHashes := 1
+	if depth > 0 {
+		numHashes = depth + 2
+	}
+
+	if len(data) != 1+pathSize+numHashes*common.HashLength {
+		return errProofEncodingInvalid
+	}
+
+	var bits uint64
+	for i := 0; i < pathSize; i++ {
+		bits |= uint64(data[1+i]) << uint(i*8)
+	}
+
+	path, err := unpackPath(bits, depth)
+	if err != nil {
+		return err
+	}
+
+	lemma := make([]common.Hash, numHashes)
+	for i, offset := 0, 1+pathSize; i < numHashes; i, offset = i+1, offset+common.HashLength {
+		lemma[i] = common.BytesToHash(data[offset : offset+common.HashLength])
+	}
+
+	proof.Lemma, proof.Path = lemma, path
+
+	return nil
+}
+
+// ABIEncode encodes proof as ABI arguments (bytes32[] lemma, uint256 path), where path is
+// the bitfield returned by PathBits, so that proof could be passed in contract calls.
+func (proof *Proof) ABIEncode() ([]byte, error) {
+	if err := proof.validateEncoding(); err != nil {
+		return nil, err
+	}
+
+	lemma := make([][32]byte, len(proof.Lemma))
+	for i, hash := range proof.Lemma {
+		lemma[i] = hash
+	}
+
+	return proofABIArguments.Pack(lemma, new(big.Int).SetUint64(proof.PathBits()))
+}
+
+// ABIDecode decodes proof from ABI arguments encoded by ABIEncode. Unused path bits set are rejected.
+func ABIDecode(data []byte) (*Proof, error) {
+	values, err := proofABIArguments.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+
+	lemma, ok := values[0].([][32]byte)
+	if !ok || len(lemma) == 0 || len(lemma) == 2 || len(lemma) > MaxProofDepth+2 {
+		return nil, errProofEncodingInvalid
+	}
+
+	bits, ok := values[1].(*big.Int)
+	if !ok || !bits.IsUint64() {
+		return nil, errProofEncodingInvalid
+	}
+
+	depth := 0
+	if len(lemma) > 1 {
+		depth = len(lemma) - 2
+	}
+
+	path, err := unpackPath(bits.Uint64(), depth)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := Proof{
+		Lemma: make([]common.Hash, len(lemma)),
+		Path:  path,
+	}
+
+	for i, hash := range lemma {
+		proof.Lemma[i] = hash
+	}
+
+	return &proof, nil
+}
+
+// sszOffsetSize is the size of offset of variable-size field in SSZ container.
+const sszOffsetSize = 4
+
+// MarshalSSZ encodes proof in SSZ as the container (lemma: List[Bytes32], path: List[bool]) of
+// proof fields. The layout follows the SSZ specification:
+//
+//	[lemma offset: uint32][path offset: uint32][lemma: 32 bytes each][path: 1 byte each]
+//
+// Offsets are in little endian and relative to the start of data, and each bool is 0x00 or 0x01.
+func (proof *Proof) MarshalSSZ() ([]byte, error) {
+	if err := proof.validateFormat(); err != nil {
+		return nil, err
+	}
+
+	lemmaOffset := 2 * sszOffsetSize
+	pathOffset := lemmaOffset + len(proof.Lemma)*common.HashLength
+
+	data := make([]byte, 2*sszOffsetSize, pathOffset+len(proof.Path))
+	binary.LittleEndian.PutUint32(data, uint32(lemmaOffset))
+	binary.LittleEndian.PutUint32(data[sszOffsetSize:], uint32(pathOffset))
+
+	for _, hash := range proof.Lemma {
+		data = append(data, hash.Bytes()...)
+	}
+
+	for _, isLeft := range proof.Path {
+		if isLeft {
+			data = append(data, 1)
+		} else {
+			data = append(data, 0)
+		}
+	}
+
+	return data, nil
+}
+
+// UnmarshalSSZ decodes proof from data encoded by MarshalSSZ. Non canonical encoding, e.g.
+// unexpected offsets or bool values other than 0x00 and 0x01, is rejected.
+func (proof *Proof) UnmarshalSSZ(data []byte) error {
+	if len(data) < 2*sszOffsetSize {
+		return errProofEncodingInvalid
+	}
+
+	lemmaOffset := binary.LittleEndian.Uint32(data)
+	pathOffset := binary.LittleEndian.Uint32(data[sszOffsetSize:])
+
+	if lemmaOffset != 2*sszOffsetSize || pathOffset < lemmaOffset || uint64(pathOffset) > uint64(len(data)) {
+		return errProofEncodingInvalid
+	}
+
+	lemmaData, pathData := data[lemmaOffset:pathOffset], data[pathOffset:]
+	if len(lemmaData)%common.HashLength != 0 {
+		return errProofEncodingInvalid
+	}
+
+	decoded := Proof{
+		Lemma: make([]common.Hash, len(lemmaData)/common.HashLength),
+		Path:  make([]bool, len(pathData)),
+	}
+
+	for i := range decoded.Lemma {
+		decoded.Lemma[i] = common.BytesToHash(lemmaData[i*common.HashLength : (i+1)*common.HashLength])
+	}
+
+	for i, v := range pathData {
+		if v > 1 {
+			return errProofEncodingInvalid
+		}
+
+		decoded.Path[i] = v == 1
+	}
+
+	if err := decoded.validateFormat(); err != nil {
+		return err
+	}
+
+	*proof = decoded
+
+	return nil
+}
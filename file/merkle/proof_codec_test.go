@@ -0,0 +1,156 @@
+package merkle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func repeatHash(b string) common.Hash {
+	return common.HexToHash(strings.Repeat(b, 32))
+}
+
+func TestProofEncodingRoundTrip(t *testing.T) {
+	for numChunks := 1; numChunks <= 40; numChunks++ {
+		tree := createTreeByChunks(numChunks)
+
+		for i := 0; i < numChunks; i++ {
+			proof := tree.ProofAt(i)
+
+			encoded, err := proof.MarshalBinary()
+			assert.NoError(t, err)
+
+			var decoded Proof
+			assert.NoError(t, decoded.UnmarshalBinary(encoded))
+			assert.Equal(t, proof, decoded)
+			assert.NoError(t, decoded.Validate(tree.Root(), createChunkData(i), uint32(i), uint32(numChunks)))
+
+			encoded, err = proof.MarshalSSZ()
+			assert.NoError(t, err)
+
+			var sszDecoded Proof
+			assert.NoError(t, sszDecoded.UnmarshalSSZ(encoded))
+			assert.Equal(t, proof, sszDecoded)
+
+			encoded, err = proof.ABIEncode()
+			assert.NoError(t, err)
+
+			abiDecoded, err := ABIDecode(encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, proof, *abiDecoded)
+		}
+	}
+}
+
+// TestProofEncodingVectors checks encodings derived by hand from the binary format, the Solidity
+// ABI specification and the SSZ specification.
+func TestProofEncodingVectors(t *testing.T) {
+	h1, h2, h3, h4 := repeatHash("11"), repeatHash("22"), repeatHash("33"), repeatHash("44")
+
+	cases := []struct {
+		proof  Proof
+		binary string
+		abi    string
+		ssz    string
+	}{
+		{
+			Proof{Lemma: []common.Hash{h1}, Path: []bool{}},
+			"0x00" + strings.Repeat("11", 32),
+			"0x" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				strings.Repeat("11", 32),
+			"0x0800000028000000" + strings.Repeat("11", 32),
+		},
+		{
+			Proof{Lemma: []common.Hash{h1, h2, h3, h4}, Path: []bool{true, false}},
+			"0x0201" + strings.Repeat("11", 32) + strings.Repeat("22", 32) + strings.Repeat("33", 32) + strings.Repeat("44", 32),
+			"0x" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000004" +
+				strings.Repeat("11", 32) + strings.Repeat("22", 32) + strings.Repeat("33", 32) + strings.Repeat("44", 32),
+			"0x0800000088000000" + strings.Repeat("11", 32) + strings.Repeat("22", 32) + strings.Repeat("33", 32) + strings.Repeat("44", 32) + "0100",
+		},
+	}
+
+	for _, v := range cases {
+		encoded, err := v.proof.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, v.binary, hexutil.Encode(encoded))
+
+		encoded, err = v.proof.ABIEncode()
+		assert.NoError(t, err)
+		assert.Equal(t, v.abi, hexutil.Encode(encoded))
+
+		encoded, err = v.proof.MarshalSSZ()
+		assert.NoError(t, err)
+		assert.Equal(t, v.ssz, hexutil.Encode(encoded))
+	}
+
+	// path bitfield spans multiple bytes
+	proof := Proof{Lemma: make([]common.Hash, 11), Path: make([]bool, 9)}
+	proof.Path[0], proof.Path[8] = true, true
+	assert.Equal(t, uint64(0x101), proof.PathBits())
+
+	encoded, err := proof.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{9, 0x01, 0x01}, encoded[:3])
+	assert.Equal(t, 3+11*common.HashLength, len(encoded))
+}
+
+func TestProofEncodingInvalid(t *testing.T) {
+	// invalid format
+	_, err := (&Proof{Lemma: []common.Hash{{}, {}}, Path: []bool{true}}).MarshalBinary()
+	assert.Equal(t, errProofWrongFormat, err)
+
+	// too deep
+	tooDeep := Proof{Lemma: make([]common.Hash, MaxProofDepth+3), Path: make([]bool, MaxProofDepth+1)}
+	_, err = tooDeep.MarshalBinary()
+	assert.Equal(t, errProofTooDeep, err)
+	_, err = tooDeep.ABIEncode()
+	assert.Equal(t, errProofTooDeep, err)
+
+	proof := Proof{Lemma: []common.Hash{repeatHash("11"), repeatHash("22"), repeatHash("33")}, Path: []bool{true}}
+	encoded, err := proof.MarshalBinary()
+	assert.NoError(t, err)
+
+	var decoded Proof
+	assert.Error(t, decoded.UnmarshalBinary(nil))
+	assert.Error(t, decoded.UnmarshalBinary(encoded[:len(encoded)-1]))
+	assert.Error(t, decoded.UnmarshalBinary(append(encoded, 0)))
+
+	// unused path bits set
+	encoded[1] |= 0x02
+	assert.Equal(t, errProofEncodingInvalid, decoded.UnmarshalBinary(encoded))
+
+	encoded, err = proof.ABIEncode()
+	assert.NoError(t, err)
+	encoded[63] |= 0x02
+	_, err = ABIDecode(encoded)
+	assert.Equal(t, errProofEncodingInvalid, err)
+
+	encoded, err = proof.MarshalSSZ()
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.UnmarshalSSZ(encoded))
+	assert.Error(t, decoded.UnmarshalSSZ(encoded[:7]))
+	assert.Error(t, decoded.UnmarshalSSZ(encoded[:len(encoded)-1]))
+	assert.Error(t, decoded.UnmarshalSSZ(append(encoded, 0)))
+
+	// invalid offsets
+	invalid := append([]byte{}, encoded...)
+	invalid[0] = 4
+	assert.Equal(t, errProofEncodingInvalid, decoded.UnmarshalSSZ(invalid))
+	invalid = append([]byte{}, encoded...)
+	invalid[4] = 0xff
+	assert.Equal(t, errProofEncodingInvalid, decoded.UnmarshalSSZ(invalid))
+
+	// invalid bool
+	invalid = append([]byte{}, encoded...)
+	invalid[len(invalid)-1] = 2
+	assert.Equal(t, errProofEncodingInvalid, decoded.UnmarshalSSZ(invalid))
+}
@@ -0,0 +1,144 @@
+package merkle
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errRangeProofWrongRange = errors.New("invalid range of merkle range proof")
+
+// RangeProof represents a compact merkle tree proof of continuous leaf nodes [start, end), which
+// only contains hashes of sibling nodes on the left and right boundaries of the range. Hashes of
+// other nodes are calculated from leaf nodes in range when validated.
+type RangeProof struct {
+	// Left contains hashes of left boundary sibling nodes from bottom to top.
+	Left []common.Hash `json:"left"`
+
+	// Right contains hashes of right boundary sibling nodes from bottom to top.
+	Right []common.Hash `json:"right"`
+}
+
+// GenerateRangeProof generates the range proof of leaf nodes [start, end) in a merkle tree with
+// numLeafNodes, where nodeAt returns the node hash at the specified level (0 for leaf nodes) and
+// index. It is useful to generate proof without building the whole merkle tree.
+func GenerateRangeProof(start, end, numLeafNodes uint32, nodeAt func(level int, index uint32) (common.Hash, error)) (RangeProof, error) {
+	proof := RangeProof{
+		Left:  []common.Hash{},
+		Right: []common.Hash{},
+	}
+
+	if start >= end || end > numLeafNodes {
+		return proof, errRangeProofWrongRange
+	}
+
+	// from bottom to top, and nodes in range are always [start, end) of each level
+	for level := 0; numLeafNodes > 1; level++ {
+		// left boundary node is on the right side
+		if start%2 == 1 {
+			hash, err := nodeAt(level, start-1)
+			if err != nil {
+				return proof, err
+			}
+
+			proof.Left = append(proof.Left, hash)
+			start--
+		}
+
+		// right boundary node is on the left side, and not the last single node
+		if end%2 == 1 && end < numLeafNodes {
+			hash, err := nodeAt(level, end)
+			if err != nil {
+				return proof, err
+			}
+
+			proof.Right = append(proof.Right, hash)
+			end++
+		}
+
+		start, end, numLeafNodes = start/2, (end+1)/2, (numLeafNodes+1)/2
+	}
+
+	return proof, nil
+}
+
+// RangeProofAt returns the range proof of leaf nodes [start, end).
+func (tree *Tree) RangeProofAt(start, end int) RangeProof {
+	if start < 0 || start >= end || end > len(tree.leafNodes) {
+		panic("range out of bound")
+	}
+
+	levels := tree.Levels()
+
+	proof, _ := GenerateRangeProof(uint32(start), uint32(end), uint32(len(tree.leafNodes)), func(level int, index uint32) (common.Hash, error) {
+		return levels[level][index], nil
+	})
+
+	return proof
+}
+
+// Validate validates the proof of contents of continuous leaf nodes starting at position.
+func (proof *RangeProof) Validate(root common.Hash, contents [][]byte, position uint32, numLeafNodes uint32, hasher ...Hasher) error {
+	hashes := make([]common.Hash, len(contents))
+	for i, v := range contents {
+		hashes[i] = hasherOrDefault(hasher...).LeafHash(v)
+	}
+
+	return proof.ValidateHashes(root, hashes, position, numLeafNodes, hasher...)
+}
+
+// ValidateHashes validates the proof of hashes of continuous leaf nodes starting at position.
+func (proof *RangeProof) ValidateHashes(root common.Hash, hashes []common.Hash, position uint32, numLeafNodes uint32, hasher ...Hasher) error {
+	start, end := position, position+uint32(len(hashes))
+	if len(hashes) == 0 || end < start || end > numLeafNodes {
+		return errRangeProofWrongRange
+	}
+
+	nodes := append([]common.Hash(nil), hashes...)
+	left, right := proof.Left, proof.Right
+
+	for numLeafNodes > 1 {
+		if start%2 == 1 {
+			if len(left) == 0 {
+				return errProofWrongFormat
+			}
+
+			nodes = append([]common.Hash{left[0]}, nodes...)
+			left = left[1:]
+			start--
+		}
+
+		if end%2 == 1 && end < numLeafNodes {
+			if len(right) == 0 {
+				return errProofWrongFormat
+			}
+
+			nodes = append(nodes, right[0])
+			right = right[1:]
+			end++
+		}
+
+		var next []common.Hash
+		for i := 0; i+1 < len(nodes); i += 2 {
+			next = append(next, hasherOrDefault(hasher...).InteriorHash(nodes[i], nodes[i+1]))
+		}
+
+		// last single node
+		if len(nodes)%2 > 0 {
+			next = append(next, nodes[len(nodes)-1])
+		}
+
+		nodes = next
+		start, end, numLeafNodes = start/2, (end+1)/2, (numLeafNodes+1)/2
+	}
+
+	if len(left) > 0 || len(right) > 0 {
+		return errProofWrongFormat
+	}
+
+	if nodes[0] != root {
+		return errProofRootMismatch
+	}
+
+	return nil
+}
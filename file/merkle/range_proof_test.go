@@ -0,0 +1,50 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeProof(t *testing.T) {
+	for numChunks := 1; numChunks <= 20; numChunks++ {
+		tree := createTreeByChunks(numChunks)
+
+		for start := 0; start < numChunks; start++ {
+			for end := start + 1; end <= numChunks; end++ {
+				var contents [][]byte
+				for i := start; i < end; i++ {
+					contents = append(contents, createChunkData(i))
+				}
+
+				proof := tree.RangeProofAt(start, end)
+				assert.NoError(t, proof.Validate(tree.Root(), contents, uint32(start), uint32(numChunks)), "chunks = %v, range = [%v, %v)", numChunks, start, end)
+
+				// wrong position
+				if start > 0 {
+					assert.Error(t, proof.Validate(tree.Root(), contents, uint32(start-1), uint32(numChunks)))
+				}
+
+				// wrong content
+				contents[len(contents)-1] = []byte("wrong")
+				assert.Error(t, proof.Validate(tree.Root(), contents, uint32(start), uint32(numChunks)))
+			}
+		}
+	}
+}
+
+func TestRangeProofSingleLeaf(t *testing.T) {
+	// range proof of single leaf contains the same siblings as proof
+	tree := createTreeByChunks(13)
+
+	for i := 0; i < 13; i++ {
+		proof := tree.ProofAt(i)
+		rangeProof := tree.RangeProofAt(i, i+1)
+
+		var siblings []common.Hash
+		siblings = append(siblings, rangeProof.Left...)
+		siblings = append(siblings, rangeProof.Right...)
+		assert.ElementsMatch(t, proof.Lemma[1:len(proof.Lemma)-1], siblings)
+	}
+}
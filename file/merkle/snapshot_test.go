@@ -0,0 +1,41 @@
+package merkle_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/vectors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	snapshotMerkleVectors = "../vectors/testdata/merkle.json"
+
+	// vectors produced by other implementations, e.g. ionian-rust, in the same format
+	externalVectorsDir = "../vectors/testdata/external"
+)
+
+// TestSnapshotVectors checks the regression snapshot of merkle tree vectors, which is generated by
+// this client and not cross-checked with other implementations.
+func TestSnapshotVectors(t *testing.T) {
+	var expected vectors.MerkleVectors
+	assert.NoError(t, vectors.Read(snapshotMerkleVectors, &expected))
+	assert.NoError(t, vectors.CheckMerkle(&expected))
+}
+
+// TestExternalVectors checks merkle tree vectors produced by other implementations, i.e.
+// merkle*.json in vectors/testdata/external. Skipped if there are no such vectors.
+func TestExternalVectors(t *testing.T) {
+	filenames, err := filepath.Glob(filepath.Join(externalVectorsDir, "merkle*.json"))
+	assert.NoError(t, err)
+
+	if len(filenames) == 0 {
+		t.Skipf("No external merkle vectors in %v", externalVectorsDir)
+	}
+
+	for _, filename := range filenames {
+		var expected vectors.MerkleVectors
+		assert.NoError(t, vectors.Read(filename, &expected))
+		assert.NoError(t, vectors.CheckMerkle(&expected), filename)
+	}
+}
@@ -8,6 +8,7 @@ import (
 type Tree struct {
 	root      *node // always not nil
 	leafNodes []*node
+	hasher    Hasher
 }
 
 func (tree *Tree) Root() common.Hash {
@@ -47,3 +48,37 @@ func (tree *Tree) ProofAt(i int) Proof {
 
 	return proof
 }
+
+// NumLeafNodes returns the number of leaf nodes.
+func (tree *Tree) NumLeafNodes() int {
+	return len(tree.leafNodes)
+}
+
+// Levels returns node hashes of all levels from bottom to top, where the single last node of
+// level is promoted to the upper level without hashing.
+func (tree *Tree) Levels() [][]common.Hash {
+	level := make([]common.Hash, len(tree.leafNodes))
+	for i, v := range tree.leafNodes {
+		level[i] = v.hash
+	}
+
+	levels := [][]common.Hash{level}
+
+	for len(level) > 1 {
+		var next []common.Hash
+
+		for i := 0; i+1 < len(level); i += 2 {
+			next = append(next, tree.hasher.InteriorHash(level[i], level[i+1]))
+		}
+
+		// last single node
+		if len(level)%2 > 0 {
+			next = append(next, level[len(level)-1])
+		}
+
+		levels = append(levels, next)
+		level = next
+	}
+
+	return levels
+}
@@ -8,11 +8,13 @@ import (
 
 // TreeBuilder is used to build complete binary merkle tree.
 type TreeBuilder struct {
+	Hasher Hasher // DefaultHasher if not specified
+
 	leafNodes []*node
 }
 
 func (builder *TreeBuilder) Append(content []byte) {
-	node := newLeafNode(content)
+	node := newLeafNode(hasherOrDefault(builder.Hasher), content)
 	builder.leafNodes = append(builder.leafNodes, node)
 }
 
@@ -27,6 +29,7 @@ func (builder *TreeBuilder) Build() *Tree {
 		return nil
 	}
 
+	hasher := hasherOrDefault(builder.Hasher)
 	queue := list.New()
 
 	for i := 0; i < numLeafNodes; i += 2 {
@@ -38,7 +41,7 @@ func (builder *TreeBuilder) Build() *Tree {
 
 		left, right := builder.leafNodes[i], builder.leafNodes[i+1]
 
-		node := newInteriorNode(left, right)
+		node := newInteriorNode(hasher, left, right)
 		queue.PushBack(node)
 	}
 
@@ -52,7 +55,7 @@ func (builder *TreeBuilder) Build() *Tree {
 			left := queue.Remove(queue.Front()).(*node)
 			right := queue.Remove(queue.Front()).(*node)
 
-			node := newInteriorNode(left, right)
+			node := newInteriorNode(hasher, left, right)
 			queue.PushBack(node)
 		}
 
@@ -65,5 +68,6 @@ func (builder *TreeBuilder) Build() *Tree {
 	return &Tree{
 		root:      queue.Front().Value.(*node),
 		leafNodes: builder.leafNodes,
+		hasher:    hasher,
 	}
 }
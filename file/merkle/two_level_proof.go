@@ -0,0 +1,78 @@
+package merkle
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TwoLevelProof represents the proof of a leaf node in a sub tree, whose root is a leaf node of the
+// top tree, e.g. proves a chunk in file with the chunk proof in segment tree and the segment proof
+// in file tree. Sub trees are built with at most a fixed number (power of 2) of leaf nodes.
+type TwoLevelProof struct {
+	Sub Proof `json:"sub"` // proof of leaf node in sub tree
+	Top Proof `json:"top"` // proof of sub tree root in top tree
+}
+
+// Validate validates the proof of content at position of all leaf nodes, e.g. chunk index in file.
+func (proof *TwoLevelProof) Validate(root common.Hash, content []byte, position, numLeafNodes, subTreeLeafNodes uint32, hasher ...Hasher) error {
+	contentHash := hasherOrDefault(hasher...).LeafHash(content)
+	return proof.ValidateHash(root, contentHash, position, numLeafNodes, subTreeLeafNodes, hasher...)
+}
+
+// ValidateHash validates the proof of content hash at position of all leaf nodes.
+func (proof *TwoLevelProof) ValidateHash(root common.Hash, contentHash common.Hash, position, numLeafNodes, subTreeLeafNodes uint32, hasher ...Hasher) error {
+	if subTreeLeafNodes == 0 || position >= numLeafNodes {
+		return errProofPositionMismatch
+	}
+
+	if len(proof.Sub.Lemma) == 0 {
+		return errProofWrongFormat
+	}
+
+	// leaf nodes of the last sub tree may be less than others
+	subTree := position / subTreeLeafNodes
+	numSubTrees := (numLeafNodes-1)/subTreeLeafNodes + 1
+	numSubLeafNodes := subTreeLeafNodes
+	if subTree == numSubTrees-1 {
+		numSubLeafNodes = numLeafNodes - subTree*subTreeLeafNodes
+	}
+
+	subRoot := proof.Sub.Lemma[len(proof.Sub.Lemma)-1]
+	if err := proof.Sub.ValidateHash(subRoot, contentHash, position%subTreeLeafNodes, numSubLeafNodes, hasher...); err != nil {
+		return fmt.Errorf("sub tree: %w", err)
+	}
+
+	if err := proof.Top.ValidateHash(root, subRoot, subTree, numSubTrees, hasher...); err != nil {
+		return fmt.Errorf("top tree: %w", err)
+	}
+
+	return nil
+}
+
+// Flatten converts to the proof of leaf node in the tree built by all leaf nodes directly, which
+// has the same root as the top tree, since sub trees have the same number (power of 2) of leaf
+// nodes except the last one.
+func (proof *TwoLevelProof) Flatten() Proof {
+	flat := Proof{
+		Lemma: []common.Hash{proof.Sub.Lemma[0]},
+		Path:  append(append([]bool{}, proof.Sub.Path...), proof.Top.Path...),
+	}
+
+	// no sibling for single node tree
+	if len(flat.Path) == 0 {
+		return flat
+	}
+
+	if len(proof.Sub.Path) > 0 {
+		flat.Lemma = append(flat.Lemma, proof.Sub.Lemma[1:len(proof.Sub.Lemma)-1]...)
+	}
+
+	if len(proof.Top.Path) > 0 {
+		flat.Lemma = append(flat.Lemma, proof.Top.Lemma[1:len(proof.Top.Lemma)-1]...)
+	}
+
+	flat.Lemma = append(flat.Lemma, proof.Top.Lemma[len(proof.Top.Lemma)-1])
+
+	return flat
+}
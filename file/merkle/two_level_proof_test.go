@@ -0,0 +1,48 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoLevelProof(t *testing.T) {
+	// sub tree with 4 chunks
+	for numChunks := 1; numChunks <= 40; numChunks++ {
+		var topBuilder TreeBuilder
+		var subTrees []*Tree
+
+		for i := 0; i < numChunks; i += 4 {
+			var subBuilder TreeBuilder
+			for j := i; j < i+4 && j < numChunks; j++ {
+				subBuilder.Append(createChunkData(j))
+			}
+
+			subTree := subBuilder.Build()
+			subTrees = append(subTrees, subTree)
+			topBuilder.AppendHash(subTree.Root())
+		}
+
+		topTree := topBuilder.Build()
+		assert.Equal(t, createTreeByChunks(numChunks).Root(), topTree.Root())
+
+		for i := 0; i < numChunks; i++ {
+			proof := TwoLevelProof{
+				Sub: subTrees[i/4].ProofAt(i % 4),
+				Top: topTree.ProofAt(i / 4),
+			}
+
+			assert.NoError(t, proof.Validate(topTree.Root(), createChunkData(i), uint32(i), uint32(numChunks), 4))
+
+			flat := proof.Flatten()
+			assert.NoError(t, flat.Validate(topTree.Root(), createChunkData(i), uint32(i), uint32(numChunks)))
+			assert.Error(t, proof.Validate(topTree.Root(), createChunkData(i+1), uint32(i), uint32(numChunks), 4))
+			assert.Error(t, proof.Validate(common.Hash{}, createChunkData(i), uint32(i), uint32(numChunks), 4))
+
+			if numChunks > 1 {
+				assert.Error(t, proof.Validate(topTree.Root(), createChunkData(i), uint32((i+1)%numChunks), uint32(numChunks), 4))
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package merkle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultZeroLeafSize is the size of all-zero leaf node for padding, i.e. chunk size of flow.
+const DefaultZeroLeafSize = 256
+
+var defaultZeroHashes = NewZeroHashes(DefaultHasher, DefaultZeroLeafSize)
+
+// ZeroHashes is a table of roots of complete subtrees with all-zero leaf nodes at each height.
+type ZeroHashes struct {
+	hasher Hasher
+	hashes []common.Hash
+}
+
+// ZeroHashesOf returns the zero hashes of the specified hasher and leaf size, which are shared for
+// DefaultHasher and DefaultZeroLeafSize, or precomputed otherwise.
+func ZeroHashesOf(hasher Hasher, leafSize int) *ZeroHashes {
+	if hasher == DefaultHasher && leafSize == DefaultZeroLeafSize {
+		return defaultZeroHashes
+	}
+
+	return NewZeroHashes(hasher, leafSize)
+}
+
+// NewZeroHashes precomputes the roots of all-zero subtrees with the specified hasher and leaf size.
+func NewZeroHashes(hasher Hasher, leafSize int) *ZeroHashes {
+	hashes := make([]common.Hash, 65)
+	hashes[0] = hasher.LeafHash(make([]byte, leafSize))
+
+	for i := 1; i < len(hashes); i++ {
+		hashes[i] = hasher.InteriorHash(hashes[i-1], hashes[i-1])
+	}
+
+	return &ZeroHashes{hasher, hashes}
+}
+
+// Hasher returns the hasher to compute zero hashes.
+func (zeros *ZeroHashes) Hasher() Hasher {
+	return zeros.hasher
+}
+
+// Hash returns the root of complete subtree with 2^height all-zero leaf nodes.
+func (zeros *ZeroHashes) Hash(height int) common.Hash {
+	return zeros.hashes[height]
+}
+
+// PaddedRoot returns the root of tree with numLeafNodes leaf nodes, where leaf nodes after the
+// specified leafHashes are all-zero leaf nodes. Padding costs O(log n) hashes, and the root is
+// identical to the tree built with all leaf nodes.
+func (zeros *ZeroHashes) PaddedRoot(leafHashes []common.Hash, numLeafNodes int) common.Hash {
+	if numLeafNodes < len(leafHashes) {
+		panic("number of leaf nodes less than leaf hashes")
+	}
+
+	if numLeafNodes == 0 {
+		return common.Hash{}
+	}
+
+	height := 0
+	for 1<<height < numLeafNodes {
+		height++
+	}
+
+	return zeros.paddedNodeHash(leafHashes, numLeafNodes, height, 0)
+}
+
+func (zeros *ZeroHashes) paddedNodeHash(leafHashes []common.Hash, numLeafNodes, height, index int) common.Hash {
+	start, end := index<<height, (index+1)<<height
+
+	if height == 0 {
+		if start < len(leafHashes) {
+			return leafHashes[start]
+		}
+
+		return zeros.hashes[0]
+	}
+
+	// complete subtree of padding
+	if start >= len(leafHashes) && end <= numLeafNodes {
+		return zeros.hashes[height]
+	}
+
+	// the last single node promoted without hashing
+	if numLeafNodes-start <= 1<<(height-1) {
+		return zeros.paddedNodeHash(leafHashes, numLeafNodes, height-1, index*2)
+	}
+
+	left := zeros.paddedNodeHash(leafHashes, numLeafNodes, height-1, index*2)
+	right := zeros.paddedNodeHash(leafHashes, numLeafNodes, height-1, index*2+1)
+
+	return zeros.hasher.InteriorHash(left, right)
+}
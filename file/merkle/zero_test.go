@@ -0,0 +1,54 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZeroHash(t *testing.T) {
+	for height := 0; height <= 6; height++ {
+		var builder TreeBuilder
+		for i := 0; i < 1<<height; i++ {
+			builder.Append(make([]byte, DefaultZeroLeafSize))
+		}
+
+		assert.Equal(t, builder.Build().Root(), defaultZeroHashes.Hash(height))
+	}
+}
+
+func TestZeroHashesOf(t *testing.T) {
+	assert.Same(t, defaultZeroHashes, ZeroHashesOf(DefaultHasher, DefaultZeroLeafSize))
+
+	// derived from the specified hasher and leaf size
+	zeros := ZeroHashesOf(sha256Hasher{}, DefaultZeroLeafSize)
+	assert.Equal(t, sha256Hasher{}, zeros.Hasher())
+	assert.Equal(t, sha256Hasher{}.LeafHash(make([]byte, DefaultZeroLeafSize)), zeros.Hash(0))
+
+	zeros = ZeroHashesOf(DefaultHasher, 128)
+	assert.Equal(t, DefaultHasher.LeafHash(make([]byte, 128)), zeros.Hash(0))
+}
+
+func TestZeroPaddedRoot(t *testing.T) {
+	for numLeafNodes := 1; numLeafNodes <= 40; numLeafNodes++ {
+		for numData := 0; numData <= numLeafNodes; numData++ {
+			var builder TreeBuilder
+			var leafHashes []common.Hash
+
+			for i := 0; i < numLeafNodes; i++ {
+				if i < numData {
+					builder.Append(createChunkData(i))
+					leafHashes = append(leafHashes, DefaultHasher.LeafHash(createChunkData(i)))
+				} else {
+					builder.Append(make([]byte, DefaultZeroLeafSize))
+				}
+			}
+
+			assert.Equal(t, builder.Build().Root(), defaultZeroHashes.PaddedRoot(leafHashes, numLeafNodes), "leaves = %v, data = %v", numLeafNodes, numData)
+		}
+	}
+
+	assert.Equal(t, common.Hash{}, defaultZeroHashes.PaddedRoot(nil, 0))
+}
@@ -0,0 +1,227 @@
+package file
+
+import (
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// NumPaddedChunks returns the number of chunks with flow padding, which are leaf nodes of the file
+// merkle tree if built by chunks.
+func (file *File) NumPaddedChunks() uint32 {
+	return uint32(file.layout.Padding(file.NumChunks()))
+}
+
+// numPaddedSegments returns the number of segments with flow padding, which are leaf nodes of the
+// file merkle tree.
+func (file *File) numPaddedSegments() uint32 {
+	return numSplits(int64(file.NumPaddedChunks()), file.layout.SegmentMaxChunks)
+}
+
+// ReadChunks reads chunks [start, end) from file, where padding chunks are filled with zeros.
+func (file *File) ReadChunks(start, end uint32) ([]byte, error) {
+	if start >= end || end > file.NumPaddedChunks() {
+		return nil, errors.Errorf("Invalid chunk range [%v, %v)", start, end)
+	}
+
+	chunkSize := int64(file.layout.ChunkSize)
+	iter := NewIterator(file.underlying, file.Size(), int64(start)*chunkSize, int64(end-start)*chunkSize, true, file.layout)
+
+	ok, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, errors.New("Unexpected end of file")
+	}
+
+	return iter.Current(), nil
+}
+
+// readSegment reads chunks of the specified segment with flow padding.
+func (file *File) readSegment(segment uint32) ([]byte, error) {
+	start, end := file.layout.segmentChunks(segment, file.NumPaddedChunks())
+	return file.ReadChunks(start, end)
+}
+
+// segmentTree builds the chunk merkle tree of the specified segment.
+func (file *File) segmentTree(segment uint32) (*merkle.Tree, error) {
+	data, err := file.readSegment(segment)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to read segment %v", segment)
+	}
+
+	chunkSize := file.layout.ChunkSize
+	builder := merkle.TreeBuilder{Hasher: file.layout.Hasher}
+	for offset := 0; offset < len(data); offset += chunkSize {
+		builder.Append(data[offset : offset+chunkSize])
+	}
+
+	return builder.Build(), nil
+}
+
+// ChunkProof generates the two-level proof of the specified chunk with file merkle tree, which
+// could be validated with position of chunk index, NumPaddedChunks and SegmentMaxChunks of layout.
+func (file *File) ChunkProof(tree *merkle.Tree, chunk uint32) (merkle.TwoLevelProof, error) {
+	if chunk >= file.NumPaddedChunks() {
+		return merkle.TwoLevelProof{}, errors.Errorf("Chunk index out of bound %v", chunk)
+	}
+
+	segmentMaxChunks := uint32(file.layout.SegmentMaxChunks)
+	segment := chunk / segmentMaxChunks
+
+	segmentTree, err := file.segmentTree(segment)
+	if err != nil {
+		return merkle.TwoLevelProof{}, err
+	}
+
+	return merkle.TwoLevelProof{
+		Sub: segmentTree.ProofAt(int(chunk % segmentMaxChunks)),
+		Top: tree.ProofAt(int(segment)),
+	}, nil
+}
+
+// RangeProof generates the range proof of chunks [start, end) with file merkle tree, which could
+// be validated with position of start chunk index and NumPaddedChunks. Note, chunk range could
+// span multiple segments, and only chunk trees of the first and last segments are built.
+//
+// Since the file merkle tree built by segment roots is the same as the tree built by chunks, it
+// could also validate a chunk range of partially downloaded segments, while the segment downloader
+// validates each segment with segment proof if DownloadOption.Proof enabled.
+func (file *File) RangeProof(tree *merkle.Tree, start, end uint32) (merkle.RangeProof, error) {
+	segmentLevelsCache := make(map[uint32][][]common.Hash)
+	fileLevels := tree.Levels()
+	segmentLevels := file.layout.segmentLevels()
+
+	return merkle.GenerateRangeProof(start, end, file.NumPaddedChunks(), func(level int, index uint32) (common.Hash, error) {
+		if level >= segmentLevels {
+			return fileLevels[level-segmentLevels][index], nil
+		}
+
+		// node in segment tree
+		nodesPerSegment := uint32(file.layout.SegmentMaxChunks >> level)
+		segment := index / nodesPerSegment
+
+		levels, ok := segmentLevelsCache[segment]
+		if !ok {
+			segmentTree, err := file.segmentTree(segment)
+			if err != nil {
+				return common.Hash{}, err
+			}
+
+			levels = segmentTree.Levels()
+			segmentLevelsCache[segment] = levels
+		}
+
+		return levels[level][index%nodesPerSegment], nil
+	})
+}
+
+// Types of content to prove in file.
+const (
+	ProofTypeChunk   = "chunk"
+	ProofTypeSegment = "segment"
+)
+
+// ContentProof is the merkle proof of a chunk or segment in file, which could be validated
+// independently with the file merkle root.
+type ContentProof struct {
+	Type         string       `json:"type"`
+	Position     uint32       `json:"position"`     // chunk or segment index
+	NumLeafNodes uint32       `json:"numLeafNodes"` // number of chunks with flow padding, or segments in file
+	Proof        merkle.Proof `json:"proof"`
+}
+
+// ContentProof generates the proof of the specified chunk or segment with file merkle tree, and
+// returns the content with padding zeros.
+func (file *File) ContentProof(tree *merkle.Tree, proofType string, index uint32) (*ContentProof, []byte, error) {
+	switch proofType {
+	case ProofTypeChunk:
+		proof, err := file.ChunkProof(tree, index)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		content, err := file.ReadChunks(index, index+1)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "Failed to read chunk")
+		}
+
+		return &ContentProof{proofType, index, file.NumPaddedChunks(), proof.Flatten()}, content, nil
+	case ProofTypeSegment:
+		numSegments := uint32(tree.NumLeafNodes())
+		if index >= numSegments {
+			return nil, nil, errors.Errorf("Segment index out of bound %v", index)
+		}
+
+		content, err := file.readSegment(index)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "Failed to read segment")
+		}
+
+		return &ContentProof{proofType, index, numSegments, tree.ProofAt(int(index))}, content, nil
+	default:
+		return nil, nil, errors.Errorf("Invalid proof type %v", proofType)
+	}
+}
+
+// Validate validates the proof of content with file merkle root, including the content position,
+// where layout is the default layout if not specified.
+func (proof *ContentProof) Validate(root common.Hash, content []byte, layout ...Layout) error {
+	l := layoutOrDefault(layout...)
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	switch proof.Type {
+	case ProofTypeChunk:
+		return proof.Proof.Validate(root, content, proof.Position, proof.NumLeafNodes, l.Hasher)
+	case ProofTypeSegment:
+		if len(content) == 0 || len(content)%l.ChunkSize > 0 || len(content) > l.SegmentSize() {
+			return errors.Errorf("Invalid segment size %v", len(content))
+		}
+
+		return proof.Proof.ValidateHash(root, l.segmentRoot(content), proof.Position, proof.NumLeafNodes, l.Hasher)
+	default:
+		return errors.Errorf("Invalid proof type %v", proof.Type)
+	}
+}
+
+// ValidatePosition validates the proof type, position and number of leaf nodes against the expected
+// chunk or segment index in a file of fileSize bytes, since they are read from the proof and could
+// not be trusted, where layout is the default layout if not specified.
+func (proof *ContentProof) ValidatePosition(proofType string, index uint32, fileSize int64, layout ...Layout) error {
+	l := layoutOrDefault(layout...)
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	if fileSize <= 0 {
+		return errors.Errorf("Invalid file size %v", fileSize)
+	}
+
+	if proof.Type != proofType {
+		return errors.Errorf("Proof type mismatch, expected = %v, actual = %v", proofType, proof.Type)
+	}
+
+	if proof.Position != index {
+		return errors.Errorf("Position mismatch, expected = %v, actual = %v", index, proof.Position)
+	}
+
+	// file merkle tree is built by chunks with flow padding, or segments of them
+	numLeafNodes := l.Padding(numSplits(fileSize, l.ChunkSize))
+	if proofType == ProofTypeSegment {
+		numLeafNodes = (numLeafNodes + uint64(l.SegmentMaxChunks) - 1) / uint64(l.SegmentMaxChunks)
+	}
+
+	if uint64(proof.NumLeafNodes) != numLeafNodes {
+		return errors.Errorf("Number of leaf nodes mismatch, expected = %v, actual = %v", numLeafNodes, proof.NumLeafNodes)
+	}
+
+	if index >= proof.NumLeafNodes {
+		return errors.Errorf("Position out of bound %v", index)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func splitChunks(data []byte) [][]byte {
+	var chunks [][]byte
+	for offset := 0; offset < len(data); offset += DefaultChunkSize {
+		chunks = append(chunks, data[offset:offset+DefaultChunkSize])
+	}
+
+	return chunks
+}
+
+func TestChunkProof(t *testing.T) {
+	for _, size := range []int{1, 1000, DefaultSegmentSize, 3*DefaultSegmentSize + 1000} {
+		file, _ := createTestFile(t, size)
+		tree, err := file.MerkleTree()
+		assert.NoError(t, err)
+
+		numChunks := file.NumPaddedChunks()
+		for _, chunk := range []uint32{0, 1, DefaultSegmentMaxChunks - 1, DefaultSegmentMaxChunks, file.NumChunks() - 1, numChunks - 1} {
+			if chunk >= numChunks {
+				continue
+			}
+
+			proof, err := file.ChunkProof(tree, chunk)
+			assert.NoError(t, err)
+
+			content, err := file.ReadChunks(chunk, chunk+1)
+			assert.NoError(t, err)
+
+			assert.NoError(t, proof.Validate(tree.Root(), content, chunk, numChunks, DefaultSegmentMaxChunks), "size = %v, chunk = %v", size, chunk)
+
+			if numChunks > 1 {
+				assert.Error(t, proof.Validate(tree.Root(), content, (chunk+1)%numChunks, numChunks, DefaultSegmentMaxChunks))
+			}
+		}
+
+		_, err = file.ChunkProof(tree, numChunks)
+		assert.Error(t, err)
+	}
+}
+
+func TestFileRangeProof(t *testing.T) {
+	file, _ := createTestFile(t, 3*DefaultSegmentSize+1000)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	numChunks := file.NumPaddedChunks()
+	ranges := [][2]uint32{
+		{0, 1},
+		{5, 17},
+		{DefaultSegmentMaxChunks - 3, DefaultSegmentMaxChunks + 5}, // span segments
+		{100, 2*DefaultSegmentMaxChunks + 7},                       // span multiple segments
+		{DefaultSegmentMaxChunks, 2 * DefaultSegmentMaxChunks},     // whole segment
+		{file.NumChunks() - 2, numChunks},                          // padding chunks
+		{0, numChunks},
+	}
+
+	for _, v := range ranges {
+		proof, err := file.RangeProof(tree, v[0], v[1])
+		assert.NoError(t, err)
+
+		data, err := file.ReadChunks(v[0], v[1])
+		assert.NoError(t, err)
+
+		assert.NoError(t, proof.Validate(tree.Root(), splitChunks(data), v[0], numChunks), "range = %v", v)
+		assert.Error(t, proof.Validate(tree.Root(), splitChunks(data), v[0]+1, numChunks))
+	}
+
+	_, err = file.RangeProof(tree, 10, numChunks+1)
+	assert.Error(t, err)
+}
+
+func TestContentProof(t *testing.T) {
+	file, _ := createTestFile(t, 3*DefaultSegmentSize+1000)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	for _, v := range []struct {
+		proofType string
+		index     uint32
+	}{
+		{ProofTypeChunk, 0},
+		{ProofTypeChunk, 2*DefaultSegmentMaxChunks + 9},
+		{ProofTypeChunk, file.NumPaddedChunks() - 1},
+		{ProofTypeSegment, 0},
+		{ProofTypeSegment, 3},
+	} {
+		proof, content, err := file.ContentProof(tree, v.proofType, v.index)
+		assert.NoError(t, err)
+		assert.NoError(t, proof.Validate(tree.Root(), content), "%v %v", v.proofType, v.index)
+		assert.NoError(t, proof.ValidatePosition(v.proofType, v.index, file.Size()), "%v %v", v.proofType, v.index)
+
+		// untrusted fields mismatch with expected content in file
+		assert.Error(t, proof.ValidatePosition(v.proofType, v.index+1, file.Size()))
+		assert.Error(t, proof.ValidatePosition(v.proofType, v.index, file.Size()+int64(file.NumPaddedChunks())*DefaultChunkSize))
+		proof.NumLeafNodes++
+		assert.Error(t, proof.ValidatePosition(v.proofType, v.index, file.Size()))
+		proof.NumLeafNodes--
+
+		// position mismatch
+		proof.Position++
+		assert.Error(t, proof.Validate(tree.Root(), content))
+		proof.Position--
+
+		// content mismatch
+		content[0]++
+		assert.Error(t, proof.Validate(tree.Root(), content))
+	}
+
+	_, _, err = file.ContentProof(tree, ProofTypeSegment, 4)
+	assert.Error(t, err)
+
+	proof, _, err := file.ContentProof(tree, ProofTypeSegment, 0)
+	assert.NoError(t, err)
+	assert.Error(t, proof.ValidatePosition(ProofTypeChunk, 0, file.Size()))
+	assert.Error(t, proof.ValidatePosition(ProofTypeSegment, 0, 0))
+}
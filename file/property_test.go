@@ -0,0 +1,412 @@
+package file
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/download"
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// naiveNextPow2 is the reference of nextPow2.
+func naiveNextPow2(input uint32) uint64 {
+	if input == 0 {
+		return 0
+	}
+
+	x := uint64(1)
+	for x < uint64(input) {
+		x *= 2
+	}
+
+	return x
+}
+
+// naiveComputePaddedSize is the reference of computePaddedSize.
+func naiveComputePaddedSize(chunks uint32) (uint64, uint64) {
+	chunksNextPow2 := naiveNextPow2(chunks)
+
+	minChunk := chunksNextPow2 / 16
+	if minChunk == 0 {
+		minChunk = 1
+	}
+
+	var paddedChunks uint64
+	for paddedChunks < uint64(chunks) {
+		paddedChunks += minChunk
+	}
+
+	return paddedChunks, chunksNextPow2
+}
+
+// naiveSplitNodes is the reference of splitNodes, which splits padded chunks by binary digits.
+func naiveSplitNodes(paddedChunks uint64) []int64 {
+	var nodes []int64
+
+	for height := 63; height >= 0; height-- {
+		if paddedChunks&(uint64(1)<<height) > 0 {
+			nodes = append(nodes, int64(1)<<height)
+		}
+	}
+
+	return nodes
+}
+
+// testChunkCounts returns number of chunks around powers of 2 and the boundaries of padding, as
+// well as random ones, so as to simulate file sizes from 1 byte to multiple GB.
+func testChunkCounts() []uint32 {
+	var counts []uint32
+
+	for height := 0; height < 32; height++ {
+		pow2 := int64(1) << height
+		for _, delta := range []int64{-1, 0, 1} {
+			for _, v := range []int64{pow2 + delta, pow2 + pow2/16 + delta, 2*pow2 - pow2/16 + delta} {
+				if v > 0 && v <= 1<<32-1 {
+					counts = append(counts, uint32(v))
+				}
+			}
+		}
+	}
+
+	for i := uint32(1); i <= 4096; i++ {
+		counts = append(counts, i)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for len(counts) < 15000 {
+		if v := r.Uint32() >> uint(r.Intn(32)); v > 0 {
+			counts = append(counts, v)
+		}
+	}
+
+	return counts
+}
+
+func TestNextPow2(t *testing.T) {
+	for _, chunks := range append(testChunkCounts(), 0, 1<<32-1) {
+		assert.Equal(t, naiveNextPow2(chunks), nextPow2(chunks), "chunks = %v", chunks)
+	}
+}
+
+func TestComputePaddedSize(t *testing.T) {
+	for _, chunks := range append(testChunkCounts(), 0, 1<<32-1) {
+		paddedChunks, chunksNextPow2 := computePaddedSize(chunks)
+		expectedPadded, expectedNextPow2 := naiveComputePaddedSize(chunks)
+		assert.Equal(t, expectedPadded, paddedChunks, "chunks = %v", chunks)
+		assert.Equal(t, expectedNextPow2, chunksNextPow2, "chunks = %v", chunks)
+
+		// padded to less than 1/16 of the next power of 2
+		assert.True(t, paddedChunks >= uint64(chunks) && paddedChunks <= chunksNextPow2, "chunks = %v", chunks)
+		assert.True(t, (paddedChunks-uint64(chunks))*16 < chunksNextPow2 || paddedChunks-uint64(chunks) == 0, "chunks = %v", chunks)
+		assert.Equal(t, paddedChunks, FlowPadding(chunks))
+	}
+}
+
+func TestSplitNodes(t *testing.T) {
+	assert.Empty(t, splitNodes(0, FlowPadding(0)))
+
+	for _, chunks := range testChunkCounts() {
+		paddedChunks := FlowPadding(chunks)
+		nodes := splitNodes(chunks, paddedChunks)
+		assert.Equal(t, naiveSplitNodes(paddedChunks), nodes, "chunks = %v", chunks)
+
+		// heights sum to padded size, and at most 4 nodes in descending order for flow padding
+		var sum int64
+		for i, v := range nodes {
+			assert.Equal(t, int64(0), v&(v-1), "chunks = %v", chunks)
+			if i > 0 {
+				assert.Less(t, v, nodes[i-1], "chunks = %v", chunks)
+			}
+
+			// aligned if the first node aligned in flow
+			assert.Equal(t, int64(0), sum%v, "chunks = %v", chunks)
+			sum += v
+		}
+		assert.Equal(t, int64(paddedChunks), sum, "chunks = %v", chunks)
+		assert.LessOrEqual(t, len(nodes), 4, "chunks = %v", chunks)
+	}
+}
+
+func TestSegmentTrimming(t *testing.T) {
+	layout := layoutOrDefault()
+	chunkSize, segmentSize := int64(layout.ChunkSize), int64(layout.SegmentSize())
+
+	for _, chunks := range testChunkCounts() {
+		for _, lastChunkSize := range []int64{1, chunkSize / 2, chunkSize} {
+			fileSize := (int64(chunks)-1)*chunkSize + lastChunkSize
+			numChunks := numSplits(fileSize, layout.ChunkSize)
+			numSegments := numSplits(fileSize, layout.SegmentSize())
+			assert.Equal(t, chunks, numChunks)
+
+			// segments are continuous and full except the last one
+			for _, segment := range []uint32{0, numSegments / 2, numSegments - 2, numSegments - 1} {
+				if segment >= numSegments {
+					continue
+				}
+
+				start, end := layout.segmentChunks(segment, numChunks)
+				assert.Equal(t, int64(segment)*segmentSize, int64(start)*chunkSize)
+				if segment < numSegments-1 {
+					assert.Equal(t, uint32(layout.SegmentMaxChunks), end-start)
+				} else {
+					assert.Equal(t, numChunks, end)
+					assert.True(t, end > start)
+				}
+			}
+
+			// downloaded chunks of the last segment are trimmed to the file size
+			start, end := layout.segmentChunks(numSegments-1, numChunks)
+			data := make([]byte, (end-start)*uint32(chunkSize))
+			assert.Equal(t, fileSize-int64(start)*chunkSize, int64(len(layout.trimLastChunk(data, fileSize))), "size = %v", fileSize)
+		}
+	}
+}
+
+// writeSparseFile creates a sparse file of the specified size, with random data in the head and
+// tail, and returns the file and data in the head and tail.
+func writeSparseFile(t *testing.T, size int64, dataSize int) (*os.File, []byte, []byte) {
+	filename := filepath.Join(t.TempDir(), "sparse")
+	f, err := os.Create(filename)
+	assert.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	assert.NoError(t, f.Truncate(size))
+
+	r := rand.New(rand.NewSource(size))
+	head, tail := make([]byte, dataSize), make([]byte, dataSize)
+	r.Read(head)
+	r.Read(tail)
+
+	_, err = f.WriteAt(head, 0)
+	assert.NoError(t, err)
+	_, err = f.WriteAt(tail, size-int64(dataSize))
+	assert.NoError(t, err)
+
+	return f, head, tail
+}
+
+// sparseContent returns the content in [offset, offset+length) of sparse file with flow padding,
+// where the tail is written after the head.
+func sparseContent(size int64, head, tail []byte, offset, length int64) []byte {
+	content := make([]byte, length)
+
+	for i := range content {
+		pos := offset + int64(i)
+		switch {
+		case pos >= size:
+		case pos >= size-int64(len(tail)):
+			content[i] = tail[pos-size+int64(len(tail))]
+		case pos < int64(len(head)):
+			content[i] = head[pos]
+		}
+	}
+
+	return content
+}
+
+func TestIteratorSparseFile(t *testing.T) {
+	layout := layoutOrDefault()
+	chunkSize, segmentSize := int64(layout.ChunkSize), int64(layout.SegmentSize())
+
+	for _, size := range []int64{5<<30 + 12345, 3<<30 - 1, 1<<32 + 1} {
+		f, head, tail := writeSparseFile(t, size, 3000)
+
+		chunks := uint32((size-1)/chunkSize + 1)
+		paddedChunks, _ := naiveComputePaddedSize(chunks)
+		paddedSize := int64(paddedChunks) * chunkSize
+
+		for _, batch := range []int64{chunkSize, segmentSize, 3 * segmentSize} {
+			// the first batch
+			iter := NewIterator(f, size, 0, batch, true, layout)
+			ok, err := iter.Next()
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, sparseContent(size, head, tail, 0, batch), iter.Current())
+
+			// a few batches around the end of file, and the last few batches of padded file
+			for _, offset := range []int64{(size - 3*batch) / batch * batch, (paddedSize - 3*batch) / batch * batch} {
+				iter = NewIterator(f, size, offset, batch, true, layout)
+				for i := 0; i < 5 && offset < paddedSize; i, offset = i+1, offset+batch {
+					length := batch
+					if offset+length > paddedSize {
+						length = paddedSize - offset
+					}
+
+					ok, err = iter.Next()
+					assert.NoError(t, err)
+					assert.True(t, ok)
+					assert.True(t, bytes.Equal(sparseContent(size, head, tail, offset, length), iter.Current()), "size = %v, offset = %v", size, offset)
+				}
+			}
+
+			ok, err = iter.Next()
+			assert.NoError(t, err)
+			assert.False(t, ok, "size = %v", size)
+
+			// without flow padding
+			iter = NewIterator(f, size, int64(chunks-1)*chunkSize, batch, false, layout)
+			ok, err = iter.Next()
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, sparseContent(size, head, tail, int64(chunks-1)*chunkSize, chunkSize), iter.Current())
+		}
+	}
+}
+
+// sparseTreeRoot is the reference root of the merkle tree of n chunks from start, where the left
+// sub tree is the largest power of 2 and sub trees of zero chunks are precomputed.
+func sparseTreeRoot(size int64, head, tail []byte, zeros []common.Hash, start, n int64) common.Hash {
+	chunkSize := int64(DefaultChunkSize)
+	begin, end := start*chunkSize, (start+n)*chunkSize
+
+	if n&(n-1) == 0 && begin >= int64(len(head)) && (end <= size-int64(len(tail)) || begin >= size) {
+		height := 0
+		for int64(1)<<height < n {
+			height++
+		}
+
+		return zeros[height]
+	}
+
+	if n == 1 {
+		return merkle.DefaultHasher.LeafHash(sparseContent(size, head, tail, begin, chunkSize))
+	}
+
+	left := int64(1)
+	for left*2 < n {
+		left *= 2
+	}
+
+	return merkle.DefaultHasher.InteriorHash(
+		sparseTreeRoot(size, head, tail, zeros, start, left),
+		sparseTreeRoot(size, head, tail, zeros, start+left, n-left),
+	)
+}
+
+func TestMerkleTreeSparseFile(t *testing.T) {
+	sizes := []int64{1, 1000, DefaultSegmentSize + 1, 33*DefaultSegmentSize - 1, 1<<30 + 12345}
+	if testing.Short() {
+		sizes = sizes[:4]
+	}
+
+	zeros := []common.Hash{merkle.DefaultHasher.LeafHash(make([]byte, DefaultChunkSize))}
+	for len(zeros) < 64 {
+		last := zeros[len(zeros)-1]
+		zeros = append(zeros, merkle.DefaultHasher.InteriorHash(last, last))
+	}
+
+	for _, size := range sizes {
+		dataSize := 3000
+		if size < int64(dataSize) {
+			dataSize = int(size)
+		}
+
+		f, head, tail := writeSparseFile(t, size, dataSize)
+		file, err := Open(f.Name())
+		assert.NoError(t, err)
+
+		tree, err := file.MerkleTree()
+		assert.NoError(t, err)
+		assert.Equal(t, sparseTreeRoot(size, head, tail, zeros, 0, int64(file.NumPaddedChunks())), tree.Root(), "size = %v", size)
+		assert.NoError(t, file.Close())
+	}
+}
+
+// testSegmentStore stores uploaded segments and serves downloads.
+type testSegmentStore struct {
+	mu           sync.Mutex
+	segmentChunk uint32
+	segments     map[uint32]node.SegmentWithProof
+}
+
+func (s *testSegmentStore) UploadSegment(segment node.SegmentWithProof) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.segments[segment.Index] = segment
+
+	return 0, nil
+}
+
+func (s *testSegmentStore) DownloadSegment(root common.Hash, startIndex, endIndex uint32) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.segments[startIndex/s.segmentChunk].Data, nil
+}
+
+func (s *testSegmentStore) DownloadSegmentWithProof(root common.Hash, index uint32) (*node.SegmentWithProof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if segment, ok := s.segments[index]; ok {
+		return &segment, nil
+	}
+
+	return nil, nil
+}
+
+func TestUploadDownloadTrimming(t *testing.T) {
+	layout := Layout{ChunkSize: 16, SegmentMaxChunks: 4}
+	segmentSize := layout.ChunkSize * layout.SegmentMaxChunks
+
+	r := rand.New(rand.NewSource(1))
+	sizes := []int{1, 15, 16, 17, segmentSize - 1, segmentSize, segmentSize + 1, 7*segmentSize + 3}
+	for i := 0; i < 20; i++ {
+		sizes = append(sizes, 1+r.Intn(50*segmentSize))
+	}
+
+	for _, size := range sizes {
+		store := testSegmentStore{segmentChunk: uint32(layout.SegmentMaxChunks), segments: make(map[uint32]node.SegmentWithProof)}
+		server := rpc.NewServer()
+		assert.NoError(t, server.RegisterName("ionian", &store))
+		httpServer := httptest.NewServer(server)
+
+		client := node.MustNewClient(httpServer.URL)
+		pool, err := node.NewPool([]*node.Client{client})
+		assert.NoError(t, err)
+
+		file, data := createTestFileWithLayout(t, size, layout)
+		tree, err := file.MerkleTree()
+		assert.NoError(t, err)
+
+		uploader := NewUploaderLight(client, UploadOption{BatchSize: 3, Routines: 2})
+		assert.NoError(t, uploader.uploadFile(file, tree))
+
+		// uploaded segments are padded to chunks
+		var uploaded int
+		for _, v := range store.segments {
+			uploaded += len(v.Data)
+		}
+		assert.Equal(t, int(file.NumChunks())*layout.ChunkSize, uploaded, "size = %v", size)
+
+		// download with or without proof
+		for _, proof := range []bool{false, true} {
+			filename := filepath.Join(t.TempDir(), "downloaded")
+			downloading, err := download.CreateDownloadingFile(filename, tree.Root(), int64(size), int64(segmentSize))
+			assert.NoError(t, err)
+
+			downloader, err := NewSegmentDownloader(pool, downloading, DownloadOption{BatchSize: 2, Layout: layout, Proof: proof})
+			assert.NoError(t, err)
+			assert.NoError(t, downloader.Download(), "size = %v, proof = %v", size, proof)
+			assert.NoError(t, downloading.Seal())
+			assert.NoError(t, downloading.Close())
+
+			downloaded, err := os.ReadFile(filename)
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(data, downloaded), "size = %v, proof = %v", size, proof)
+		}
+
+		pool.Close()
+		httpServer.Close()
+	}
+}
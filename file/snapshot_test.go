@@ -0,0 +1,41 @@
+package file_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/vectors"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	snapshotFileVectors = "vectors/testdata/file.json"
+
+	// vectors produced by other implementations, e.g. ionian-rust, in the same format
+	externalVectorsDir = "vectors/testdata/external"
+)
+
+// TestSnapshotVectors checks the regression snapshot of file vectors, which is generated by this
+// client and not cross-checked with other implementations.
+func TestSnapshotVectors(t *testing.T) {
+	var expected vectors.FileVectors
+	assert.NoError(t, vectors.Read(snapshotFileVectors, &expected))
+	assert.NoError(t, vectors.CheckFiles(t.TempDir(), &expected))
+}
+
+// TestExternalVectors checks file vectors produced by other implementations, i.e. file*.json in
+// vectors/testdata/external. Skipped if there are no such vectors.
+func TestExternalVectors(t *testing.T) {
+	filenames, err := filepath.Glob(filepath.Join(externalVectorsDir, "file*.json"))
+	assert.NoError(t, err)
+
+	if len(filenames) == 0 {
+		t.Skipf("No external file vectors in %v", externalVectorsDir)
+	}
+
+	for _, filename := range filenames {
+		var expected vectors.FileVectors
+		assert.NoError(t, vectors.Read(filename, &expected))
+		assert.NoError(t, vectors.CheckFiles(t.TempDir(), &expected), filename)
+	}
+}
@@ -0,0 +1,187 @@
+package file
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	treeCacheMagic   = "IOMC"
+	treeCacheVersion = 1
+
+	// [magic][version][size][mtime][inode][numSegments][root]...[crc]
+	treeCacheHeaderSize = 4 + 4 + 8 + 8 + 8 + 4 + common.HashLength
+
+	// Files modified recently are not cached, since file could be changed again within the
+	// granularity of mtime while size unchanged.
+	treeCacheRacyInterval = 2 * time.Second
+)
+
+// treeCacheKey identifies a version of local file.
+type treeCacheKey struct {
+	size  uint64
+	mtime uint64 // in nanoseconds
+	inode uint64
+}
+
+// treeCacheKey returns the cache key of file, or false if inode number unavailable, in which case
+// file replaced with the same size and mtime could not be detected.
+func (file *File) treeCacheKey() (treeCacheKey, bool) {
+	ino, ok := inode(file.underlying, file.FileInfo)
+	if !ok {
+		return treeCacheKey{}, false
+	}
+
+	return treeCacheKey{
+		size:  uint64(file.Size()),
+		mtime: uint64(file.ModTime().UnixNano()),
+		inode: ino,
+	}, true
+}
+
+// treeCacheEnabled returns whether to cache merkle tree of file.
+func (file *File) treeCacheEnabled() bool {
+	if len(file.layout.MerkleCacheDir) == 0 {
+		return false
+	}
+
+	_, ok := file.treeCacheKey()
+
+	return ok
+}
+
+// treeCacheFilename returns the cache filename of file path and layout, where layout is identified by
+// the all-zero chunk hash, segment max chunks and number of padded chunks.
+func (file *File) treeCacheFilename() string {
+	var layout [16]byte
+	binary.BigEndian.PutUint64(layout[:], uint64(file.layout.SegmentMaxChunks))
+	binary.BigEndian.PutUint64(layout[8:], file.layout.Padding(file.NumChunks()))
+
+	hash := crypto.Keccak256Hash([]byte(file.path), file.layout.zeros.Hash(0).Bytes(), layout[:])
+
+	return filepath.Join(file.layout.MerkleCacheDir, hash.Hex()[2:]+".merkle")
+}
+
+// loadCachedTree returns the cached merkle tree, or nil if cache missed or invalidated.
+func (file *File) loadCachedTree() *merkle.Tree {
+	data, err := ioutil.ReadFile(file.treeCacheFilename())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.WithError(err).WithField("file", file.path).Debug("Failed to read merkle tree cache")
+		}
+
+		return nil
+	}
+
+	key, _ := file.treeCacheKey()
+	// segment roots are cached with padding segments
+	tree, err := decodeTreeCache(data, key, file.numPaddedSegments(), file.layout.Hasher)
+	if err != nil {
+		logrus.WithError(err).WithField("file", file.path).Debug("Merkle tree cache invalidated")
+		return nil
+	}
+
+	return tree
+}
+
+// cacheTree caches the segment roots and root of file merkle tree, and any error is ignored.
+func (file *File) cacheTree(segmentRoots []common.Hash, root common.Hash) {
+	if time.Since(file.ModTime()) < treeCacheRacyInterval {
+		return
+	}
+
+	dir := file.layout.MerkleCacheDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logrus.WithError(err).WithField("dir", dir).Warn("Failed to create merkle tree cache dir")
+		return
+	}
+
+	key, _ := file.treeCacheKey()
+	data := encodeTreeCache(key, segmentRoots, root)
+
+	filename := file.treeCacheFilename()
+	tmpFilename := filename + ".tmp"
+	if err := ioutil.WriteFile(tmpFilename, data, 0644); err != nil {
+		logrus.WithError(err).WithField("file", file.path).Warn("Failed to write merkle tree cache")
+		return
+	}
+
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		logrus.WithError(err).WithField("file", file.path).Warn("Failed to write merkle tree cache")
+	}
+}
+
+func encodeTreeCache(key treeCacheKey, segmentRoots []common.Hash, root common.Hash) []byte {
+	data := make([]byte, treeCacheHeaderSize, treeCacheHeaderSize+len(segmentRoots)*common.HashLength+4)
+
+	copy(data, treeCacheMagic)
+	binary.BigEndian.PutUint32(data[4:], treeCacheVersion)
+	binary.BigEndian.PutUint64(data[8:], key.size)
+	binary.BigEndian.PutUint64(data[16:], key.mtime)
+	binary.BigEndian.PutUint64(data[24:], key.inode)
+	binary.BigEndian.PutUint32(data[32:], uint32(len(segmentRoots)))
+	copy(data[36:], root.Bytes())
+
+	for _, v := range segmentRoots {
+		data = append(data, v.Bytes()...)
+	}
+
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(data))
+
+	return append(data, crc[:]...)
+}
+
+func decodeTreeCache(data []byte, key treeCacheKey, numSegments uint32, hasher merkle.Hasher) (*merkle.Tree, error) {
+	if len(data) < treeCacheHeaderSize+4 || string(data[:4]) != treeCacheMagic {
+		return nil, errors.New("Invalid cache format")
+	}
+
+	if version := binary.BigEndian.Uint32(data[4:]); version != treeCacheVersion {
+		return nil, errors.Errorf("Unsupported cache version %v", version)
+	}
+
+	content, crc := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(content) != crc {
+		return nil, errors.New("Cache checksum mismatch")
+	}
+
+	cachedKey := treeCacheKey{
+		size:  binary.BigEndian.Uint64(data[8:]),
+		mtime: binary.BigEndian.Uint64(data[16:]),
+		inode: binary.BigEndian.Uint64(data[24:]),
+	}
+	if cachedKey != key {
+		return nil, errors.New("File changed")
+	}
+
+	if n := binary.BigEndian.Uint32(data[32:]); n != numSegments || n == 0 {
+		return nil, errors.Errorf("Invalid number of segments %v", n)
+	}
+
+	if len(content) != treeCacheHeaderSize+int(numSegments)*common.HashLength {
+		return nil, errors.New("Invalid cache size")
+	}
+
+	builder := merkle.TreeBuilder{Hasher: hasher}
+	for offset := treeCacheHeaderSize; offset < len(content); offset += common.HashLength {
+		builder.AppendHash(common.BytesToHash(content[offset : offset+common.HashLength]))
+	}
+
+	tree := builder.Build()
+	if root := common.BytesToHash(data[36:treeCacheHeaderSize]); tree.Root() != root {
+		return nil, errors.New("Cache root mismatch")
+	}
+
+	return tree, nil
+}
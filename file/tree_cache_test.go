@@ -0,0 +1,132 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// createCachedTestFile creates a test file with merkle tree cache enabled.
+func createCachedTestFile(t *testing.T, size int) (*File, []byte) {
+	return createTestFileWithLayout(t, size, Layout{MerkleCacheDir: t.TempDir()})
+}
+
+// reopenFile writes data into file with the specified mtime and opens it again.
+func reopenFile(t *testing.T, file *File, data []byte, mtime time.Time) *File {
+	assert.NoError(t, os.WriteFile(file.path, data, 0644))
+	assert.NoError(t, os.Chtimes(file.path, mtime, mtime))
+
+	reopened, err := Open(file.path, file.Layout())
+	assert.NoError(t, err)
+	t.Cleanup(func() { reopened.Close() })
+
+	return reopened
+}
+
+func TestTreeCache(t *testing.T) {
+	file, data := createCachedTestFile(t, 3*DefaultSegmentSize+1000)
+	mtime := time.Now().Add(-time.Minute)
+	file = reopenFile(t, file, data, mtime)
+
+	expected, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	hits := testutil.ToFloat64(metrics.MerkleCacheHits)
+
+	// load from cache
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Root(), tree.Root())
+	assert.Equal(t, expected.Levels(), tree.Levels())
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.MerkleCacheHits))
+
+	// invalidated once file changed with the same size
+	data[0]++
+	file = reopenFile(t, file, data, mtime.Add(time.Second))
+
+	tree, err = file.MerkleTree()
+	assert.NoError(t, err)
+	assert.NotEqual(t, expected.Root(), tree.Root())
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.MerkleCacheHits))
+	expected = tree
+
+	// invalidated if cache corrupted
+	cacheFilename := file.treeCacheFilename()
+	cache, err := os.ReadFile(cacheFilename)
+	assert.NoError(t, err)
+	cache[treeCacheHeaderSize]++
+	assert.NoError(t, os.WriteFile(cacheFilename, cache, 0644))
+
+	tree, err = file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Root(), tree.Root())
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.MerkleCacheHits))
+
+	// cache recovered
+	_, err = file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, hits+2, testutil.ToFloat64(metrics.MerkleCacheHits))
+}
+
+func TestTreeCachePaddingSegments(t *testing.T) {
+	file, data := createCachedTestFile(t, 17*DefaultSegmentSize)
+	file = reopenFile(t, file, data, time.Now().Add(-time.Minute))
+	assert.Less(t, file.NumSegments(), file.numPaddedSegments())
+
+	expected, err := file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, int(file.numPaddedSegments()), expected.NumLeafNodes())
+
+	hits := testutil.ToFloat64(metrics.MerkleCacheHits)
+
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Levels(), tree.Levels())
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.MerkleCacheHits))
+}
+
+func TestTreeCacheRecentlyModified(t *testing.T) {
+	file, _ := createCachedTestFile(t, 1000)
+
+	_, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	_, err = os.Stat(file.treeCacheFilename())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTreeCacheDisabled(t *testing.T) {
+	// cache disabled by default
+	file, data := createTestFile(t, 1000)
+	file = reopenFile(t, file, data, time.Now().Add(-time.Minute))
+	assert.False(t, file.treeCacheEnabled())
+
+	// cached by absolute path
+	dir := t.TempDir()
+	file, err := Open(file.path, Layout{MerkleCacheDir: dir})
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.True(t, file.treeCacheEnabled())
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(filepath.Dir(file.path)))
+	defer os.Chdir(wd)
+
+	relative, err := Open(filepath.Base(file.path), Layout{MerkleCacheDir: dir})
+	assert.NoError(t, err)
+	defer relative.Close()
+	assert.Equal(t, file.treeCacheFilename(), relative.treeCacheFilename())
+
+	_, err = relative.MerkleTree()
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
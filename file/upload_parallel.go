@@ -0,0 +1,139 @@
+package file
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/common/parallel"
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxUploadRetries is the maximum retries to upload a batch of segments for retryable errors.
+const maxUploadRetries = 3
+
+// segmentUploader uploads segments to a storage node concurrently, where each task uploads a
+// batch of segments.
+type segmentUploader struct {
+	uploader *Uploader
+	file     *File
+	root     common.Hash
+	proofs   []merkle.Proof // prepared ahead for all segments with real data
+
+	numChunks   uint32
+	numSegments uint32
+	batchSize   uint32
+
+	uploaded uint32 // number of uploaded segments
+}
+
+func newSegmentUploader(uploader *Uploader, file *File, tree *merkle.Tree) *segmentUploader {
+	numSegments := file.NumSegments()
+
+	proofs := make([]merkle.Proof, numSegments)
+	for i := range proofs {
+		proofs[i] = tree.ProofAt(i)
+	}
+
+	return &segmentUploader{
+		uploader: uploader,
+		file:     file,
+		root:     tree.Root(),
+		proofs:   proofs,
+
+		numChunks:   file.NumChunks(),
+		numSegments: numSegments,
+		batchSize:   uint32(uploader.option.BatchSize),
+	}
+}
+
+// Upload uploads all segments with real data in parallel, and rear padding segments are skipped.
+func (su *segmentUploader) Upload() error {
+	numTasks := (su.numSegments + su.batchSize - 1) / su.batchSize
+	routines := su.uploader.option.Routines
+
+	return parallel.Execute(su, int(numTasks), parallel.Option{
+		Routines: routines,
+		Window:   routines * 2,
+		// upload is complete once file finalized, so it is safe to upload again
+		Retry: &parallel.ExponentialBackoff{
+			MaxRetries: maxUploadRetries,
+			Interval:   time.Second,
+			Retryable:  node.IsRetryable,
+		},
+	})
+}
+
+// ParallelDo implements the parallel.Interface interface.
+func (su *segmentUploader) ParallelDo(routine, task int) (interface{}, error) {
+	startSegment := uint32(task) * su.batchSize
+	endSegment := startSegment + su.batchSize
+	if endSegment > su.numSegments {
+		endSegment = su.numSegments
+	}
+
+	segments, err := su.readSegments(startSegment, endSegment)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to read segments [%v, %v)", startSegment, endSegment)
+	}
+
+	if err = su.uploader.uploadSegments(su.file, segments); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"routine":  routine,
+			"segments": fmt.Sprintf("[%v, %v)/%v", startSegment, endSegment, su.numSegments),
+		}).Error("Failed to upload segments")
+
+		return nil, err
+	}
+
+	return len(segments), nil
+}
+
+// readSegments reads segments [start, end) from file, and pads the last chunk with zeros if any.
+func (su *segmentUploader) readSegments(start, end uint32) ([]node.SegmentWithProof, error) {
+	layout := su.file.layout
+	segmentSize := uint32(layout.SegmentSize())
+
+	offset := int64(start) * int64(segmentSize)
+	iter := NewIterator(su.file.underlying, su.file.Size(), offset, int64(end-start)*int64(segmentSize), true, layout)
+
+	ok, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, errors.New("Unexpected end of file")
+	}
+
+	data := iter.Current()
+
+	var segments []node.SegmentWithProof
+	for i := start; i < end; i++ {
+		startChunk, endChunk := layout.segmentChunks(i, su.numChunks)
+		dataOffset := (i - start) * segmentSize
+		segments = append(segments, node.SegmentWithProof{
+			Root:  su.root,
+			Data:  data[dataOffset : dataOffset+(endChunk-startChunk)*uint32(layout.ChunkSize)],
+			Index: i,
+			Proof: su.proofs[i],
+		})
+	}
+
+	return segments, nil
+}
+
+// ParallelCollect implements the parallel.Interface interface.
+func (su *segmentUploader) ParallelCollect(result *parallel.Result) error {
+	su.uploaded += uint32(result.Value.(int))
+
+	logrus.WithFields(logrus.Fields{
+		"uploaded": su.uploaded,
+		"total":    su.numSegments,
+	}).Debug("Segments uploaded")
+
+	return nil
+}
@@ -3,6 +3,7 @@ package file
 import (
 	"time"
 
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
 	"github.com/Ionian-Web3-Storage/ionian-client/contract"
 	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
 	"github.com/Ionian-Web3-Storage/ionian-client/node"
@@ -15,27 +16,76 @@ import (
 // maxDataSize is the maximum data size to upload on blockchain directly.
 // const maxDataSize = int64(4 * 1024)
 
+// UploadOption is the option to upload file to storage node.
+type UploadOption struct {
+	BatchSize int // number of segments to upload in a JSON-RPC batch, 1 to upload segments one by one
+	// Number of concurrent upload RPCs to storage node. Note, segments are read from file by each
+	// routine, so the memory to upload is bounded by Routines * BatchSize * segment size.
+	Routines int
+	Layout   Layout // file layout, zero value fields use the default layout
+}
+
+// DefaultUploadOption returns the default option to upload file.
+func DefaultUploadOption() UploadOption {
+	return UploadOption{
+		BatchSize: 4,
+		Routines:  4,
+	}
+}
+
 type Uploader struct {
 	ionian *contract.Flow
 	client *node.Client
+	option UploadOption
 }
 
-func NewUploader(ionian *contract.Flow, client *node.Client) *Uploader {
+func NewUploader(ionian *contract.Flow, client *node.Client, option ...UploadOption) *Uploader {
 	return &Uploader{
 		ionian: ionian,
 		client: client,
+		option: uploadOptionOrDefault(option),
 	}
 }
 
-func NewUploaderLight(client *node.Client) *Uploader {
+func NewUploaderLight(client *node.Client, option ...UploadOption) *Uploader {
 	return &Uploader{
 		client: client,
+		option: uploadOptionOrDefault(option),
 	}
 }
 
+// NewUploaderWithPool creates an uploader to upload file to the best healthy storage node in pool.
+// Note, ionian contract could be nil if log entry already submitted on blockchain.
+func NewUploaderWithPool(ionian *contract.Flow, pool *node.Pool, option ...UploadOption) (*Uploader, error) {
+	client := pool.Select()
+	if client == nil {
+		return nil, errors.New("No healthy storage node available")
+	}
+
+	return NewUploader(ionian, client, option...), nil
+}
+
+func uploadOptionOrDefault(option []UploadOption) UploadOption {
+	if len(option) == 0 {
+		return DefaultUploadOption()
+	}
+
+	opt := option[0]
+
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 1
+	}
+
+	if opt.Routines <= 0 {
+		opt.Routines = 1
+	}
+
+	return opt
+}
+
 func (uploader *Uploader) Upload(filename string) error {
 	// Open file to upload
-	file, err := Open(filename)
+	file, err := Open(filename, uploader.option.Layout)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to open file")
 	}
@@ -127,11 +177,16 @@ func (uploader *Uploader) Upload(filename string) error {
 func (uploader *Uploader) waitForSuccessfulExecution(txHash common.Hash) error {
 	logrus.WithField("tx", txHash).Info("Wait for transaction execution")
 
+	start := time.Now()
+
 	receipt, err := uploader.ionian.WaitForReceipt(txHash)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to wait for receipt")
 	}
 
+	metrics.TxSubmitDuration.Observe(metrics.Since(start))
+	metrics.TxGasUsed.Observe(float64(receipt.GasUsed))
+
 	if receipt.Status == nil {
 		return errors.New("status not found in receipt")
 	}
@@ -190,69 +245,64 @@ func (uploader *Uploader) waitForLogEntry(root common.Hash) error {
 
 // TODO error tolerance
 func (uploader *Uploader) uploadFile(file *File, tree *merkle.Tree) error {
-	logrus.Info("Begin to upload file")
+	logrus.WithFields(logrus.Fields{
+		"batch":    uploader.option.BatchSize,
+		"routines": uploader.option.Routines,
+	}).Info("Begin to upload file")
 
-	iter := file.Iterate(true)
-	var segIndex int
+	if err := newSegmentUploader(uploader, file, tree).Upload(); err != nil {
+		return err
+	}
 
-	for {
-		ok, err := iter.Next()
-		if err != nil {
-			return errors.WithMessage(err, "Failed to read segment")
-		}
+	logrus.Info("Completed to upload file")
 
-		if !ok {
-			break
-		}
+	return nil
+}
 
-		segment := iter.Current()
-		proof := tree.ProofAt(segIndex)
+// uploadSegments uploads segments of file in a JSON-RPC batch if more than one segment specified.
+// Once the file is finalized on storage node, all segments have been uploaded, and the remaining
+// segments are skipped.
+func (uploader *Uploader) uploadSegments(file *File, segments []node.SegmentWithProof) error {
+	var errs []error
 
-		// Skip upload rear padding data
-		numChunks := file.NumChunks()
-		startIndex := segIndex * DefaultSegmentMaxChunks
-		allDataUploaded := false
-		if startIndex >= int(numChunks) {
-			// file real data already uploaded
-			break
-		} else if startIndex+len(segment)/DefaultChunkSize >= int(numChunks) {
-			// last segment has real data
-			expectedLen := DefaultChunkSize * (int(numChunks) - startIndex)
-			segment = segment[:expectedLen]
-			allDataUploaded = true
+	switch len(segments) {
+	case 0:
+		return nil
+	case 1:
+		_, err := uploader.client.UploadSegment(segments[0])
+		errs = []error{err}
+	default:
+		var err error
+		if errs, err = uploader.client.UploadSegments(segments); err != nil {
+			return errors.WithMessagef(err, "Failed to upload segments [%v, %v]", segments[0].Index, segments[len(segments)-1].Index)
 		}
+	}
 
-		segWithProof := node.SegmentWithProof{
-			Root:  tree.Root(),
-			Data:  segment,
-			Index: uint32(segIndex),
-			Proof: proof,
+	for i, v := range segments {
+		if node.IsErrorCode(errs[i], node.ErrCodeFileFinalized) {
+			logrus.WithError(errs[i]).WithField("index", v.Index).Debug("File already finalized")
+			return nil
 		}
 
-		if _, err = uploader.client.UploadSegment(segWithProof); err != nil {
-			return errors.WithMessage(err, "Failed to upload segment")
+		if errs[i] != nil {
+			return errors.WithMessagef(errs[i], "Failed to upload segment %v", v.Index)
 		}
 
+		metrics.UploadSegments.WithLabelValues(uploader.client.URL()).Inc()
+		metrics.UploadBytes.WithLabelValues(uploader.client.URL()).Add(float64(len(v.Data)))
+
 		if logrus.IsLevelEnabled(logrus.DebugLevel) {
-			chunkIndex := segIndex * DefaultSegmentMaxChunks
+			chunkIndex := int(v.Index) * file.layout.SegmentMaxChunks
 			logrus.WithFields(logrus.Fields{
 				"total":      file.NumSegments(),
-				"index":      segIndex,
+				"index":      v.Index,
 				"chunkStart": chunkIndex,
-				"chunkEnd":   chunkIndex + len(segment)/DefaultChunkSize,
-				"root":       segmentRoot(segment),
+				"chunkEnd":   chunkIndex + len(v.Data)/file.layout.ChunkSize,
+				"root":       file.layout.segmentRoot(v.Data),
 			}).Debug("Segment uploaded")
 		}
-
-		if allDataUploaded {
-			break
-		}
-
-		segIndex++
 	}
 
-	logrus.Info("Completed to upload file")
-
 	return nil
 }
 
@@ -0,0 +1,175 @@
+package file
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+type testUploadService struct {
+	mu       sync.Mutex
+	segments map[uint32]node.SegmentWithProof
+
+	// log entry of file available on storage node, which is finalized once all segments uploaded
+	root        common.Hash
+	numSegments int
+
+	finalized bool // file finalized already, and all uploads are rejected
+}
+
+// testFileFinalizedError is the JSON-RPC error of storage node when file already finalized.
+type testFileFinalizedError struct{}
+
+func (testFileFinalizedError) Error() string {
+	return `Invalid parameter root: "already uploaded and finalized"`
+}
+func (testFileFinalizedError) ErrorCode() int { return -32602 }
+
+func (s *testUploadService) GetFileInfo(root common.Hash) (*node.FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if root != s.root {
+		return nil, nil
+	}
+
+	return &node.FileInfo{
+		Tx:        node.Transaction{DataMerkleRoot: root},
+		Finalized: len(s.segments) == s.numSegments,
+	}, nil
+}
+
+func (s *testUploadService) UploadSegment(segment node.SegmentWithProof) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.finalized {
+		return 0, testFileFinalizedError{}
+	}
+
+	s.segments[segment.Index] = segment
+
+	return 0, nil
+}
+
+func createTestFile(t *testing.T, size int) (*File, []byte) {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	filename := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(filename, data, 0644))
+
+	file, err := Open(filename)
+	assert.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	return file, data
+}
+
+func TestUploadFile(t *testing.T) {
+	service := testUploadService{segments: make(map[uint32]node.SegmentWithProof)}
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &service))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := node.MustNewClient(httpServer.URL)
+	defer client.Close()
+
+	file, data := createTestFile(t, 7*DefaultSegmentSize+1000)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	uploader := NewUploaderLight(client, UploadOption{BatchSize: 2, Routines: 3})
+	assert.NoError(t, uploader.uploadFile(file, tree))
+	assert.Equal(t, int(file.NumSegments()), len(service.segments))
+
+	for i := 0; i < int(file.NumSegments()); i++ {
+		segment := service.segments[uint32(i)]
+		assert.Equal(t, tree.Root(), segment.Root)
+		assert.Equal(t, tree.ProofAt(i), segment.Proof)
+
+		start := i * DefaultSegmentSize
+		end := start + DefaultSegmentSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		// last chunk padded with zeros
+		expected := data[start:end]
+		if padding := len(expected) % DefaultChunkSize; padding > 0 {
+			expected = append(expected, make([]byte, DefaultChunkSize-padding)...)
+		}
+
+		assert.True(t, bytes.Equal(expected, segment.Data), "segment %v", i)
+	}
+}
+
+func TestUploadWithLayout(t *testing.T) {
+	layout := Layout{ChunkSize: 128, SegmentMaxChunks: 16, Hasher: sha256Hasher{}}
+	file, _ := createTestFileWithLayout(t, 5*16*128+300, layout)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	service := testUploadService{
+		segments:    make(map[uint32]node.SegmentWithProof),
+		root:        tree.Root(),
+		numSegments: int(file.NumSegments()),
+	}
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &service))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := node.MustNewClient(httpServer.URL)
+	defer client.Close()
+
+	// file root mismatch with the default layout
+	assert.Error(t, NewUploaderLight(client).Upload(file.path))
+	assert.Empty(t, service.segments)
+
+	assert.NoError(t, NewUploaderLight(client, UploadOption{BatchSize: 2, Routines: 2, Layout: layout}).Upload(file.path))
+	assert.Equal(t, 6, len(service.segments))
+
+	var uploaded int
+	for i, segment := range service.segments {
+		assert.Equal(t, tree.ProofAt(int(i)), segment.Proof)
+		uploaded += len(segment.Data)
+	}
+
+	// last chunk padded with zeros
+	assert.Equal(t, 5*16*128+384, uploaded)
+
+	// negative values are rejected rather than replaced with default layout
+	err = NewUploaderLight(client, UploadOption{Layout: Layout{ChunkSize: -1}}).Upload(file.path)
+	assert.Error(t, err)
+}
+
+func TestUploadFileFinalized(t *testing.T) {
+	service := testUploadService{segments: make(map[uint32]node.SegmentWithProof), finalized: true}
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &service))
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := node.MustNewClient(httpServer.URL)
+	defer client.Close()
+
+	file, _ := createTestFile(t, 3*DefaultSegmentSize+1000)
+	tree, err := file.MerkleTree()
+	assert.NoError(t, err)
+
+	// file finalized by another uploader
+	uploader := NewUploaderLight(client, UploadOption{BatchSize: 2, Routines: 2})
+	assert.NoError(t, uploader.uploadFile(file, tree))
+	assert.Empty(t, service.segments)
+}
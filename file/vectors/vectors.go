@@ -0,0 +1,488 @@
+// Package vectors generates and checks test vectors of file merkle roots, proofs and flow submissions.
+//
+// Vectors are fully determined by the file content or leaf data that could be reproduced from
+// parameters in vectors. The checked-in vectors in testdata are regression snapshots generated by
+// this client, and are not cross-checked with ionian-rust yet. Vectors produced by other
+// implementations in the same format could be checked by CheckFiles and CheckMerkle.
+package vectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/file"
+	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// SubmissionNode is a node of flow submission.
+type SubmissionNode struct {
+	Root   common.Hash `json:"root"`
+	Height uint64      `json:"height"`
+}
+
+// FileVector is the test vector of a file in the default layout, whose content is generated by
+// FileContent with the size and seed.
+type FileVector struct {
+	Size            int64               `json:"size"`
+	Seed            uint64              `json:"seed"`
+	NumChunks       uint32              `json:"numChunks"`
+	NumPaddedChunks uint32              `json:"numPaddedChunks"`
+	Root            common.Hash         `json:"root"`
+	SegmentRoots    []common.Hash       `json:"segmentRoots"`
+	Proofs          []file.ContentProof `json:"proofs"`
+	Submission      []SubmissionNode    `json:"submission"`
+}
+
+// FileVectors is a suite of file test vectors.
+type FileVectors struct {
+	Comment string       `json:"comment"`
+	Files   []FileVector `json:"files"`
+}
+
+// ProofVector is the test vector of a leaf proof in merkle tree.
+type ProofVector struct {
+	Index  int           `json:"index"`
+	Proof  merkle.Proof  `json:"proof"`
+	Binary hexutil.Bytes `json:"binary"` // proof in compact binary format of client
+	ABI    hexutil.Bytes `json:"abi"`    // proof in ABI arguments (bytes32[] lemma, uint256 path)
+	SSZ    hexutil.Bytes `json:"ssz"`    // proof in SSZ
+}
+
+// TreeVector is the test vector of merkle tree, whose leaf i is generated by LeafContent(i).
+type TreeVector struct {
+	NumLeaves int           `json:"numLeaves"`
+	Root      common.Hash   `json:"root"`
+	Proofs    []ProofVector `json:"proofs"`
+}
+
+// MerkleVectors is a suite of merkle tree test vectors.
+type MerkleVectors struct {
+	Comment    string        `json:"comment"`
+	ZeroHashes []common.Hash `json:"zeroHashes"` // root of 2^height zero chunks of 256 bytes
+	Trees      []TreeVector  `json:"trees"`
+}
+
+// FileContent returns the file content of test vector, where byte i is (i * seed) % 251.
+func FileContent(size int64, seed uint64) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(uint64(i) * seed % 251)
+	}
+
+	return data
+}
+
+// LeafContent returns the leaf data of merkle tree test vector, which is "chunk data - i".
+func LeafContent(i int) []byte {
+	return []byte(fmt.Sprintf("chunk data - %v", i))
+}
+
+// WriteFile writes the file content of test vector into dir, and returns the file name.
+func WriteFile(dir string, size int64, seed uint64) (string, error) {
+	filename := filepath.Join(dir, fmt.Sprintf("vector-%v-%v", size, seed))
+	if err := ioutil.WriteFile(filename, FileContent(size, seed), 0644); err != nil {
+		return "", errors.WithMessage(err, "Failed to write file")
+	}
+
+	return filename, nil
+}
+
+// GenerateFile generates the test vector of file with the specified size and seed, where dir is
+// used to write file content temporarily.
+func GenerateFile(dir string, size int64, seed uint64) (*FileVector, error) {
+	filename, err := WriteFile(dir, size, seed)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filename)
+
+	f, err := file.Open(filename)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open file")
+	}
+	defer f.Close()
+
+	tree, err := f.MerkleTree()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create file merkle tree")
+	}
+
+	vector := FileVector{
+		Size:            size,
+		Seed:            seed,
+		NumChunks:       f.NumChunks(),
+		NumPaddedChunks: f.NumPaddedChunks(),
+		Root:            tree.Root(),
+		SegmentRoots:    tree.Levels()[0],
+	}
+
+	numSegments := uint32(tree.NumLeafNodes())
+	proofs := []struct {
+		proofType string
+		index     uint32
+	}{
+		{file.ProofTypeChunk, 0},
+		{file.ProofTypeChunk, f.NumChunks() - 1},
+		{file.ProofTypeChunk, f.NumPaddedChunks() - 1},
+		{file.ProofTypeSegment, 0},
+		{file.ProofTypeSegment, numSegments - 1},
+	}
+
+	for i, v := range proofs {
+		// skip duplicated proofs for small files
+		if i > 0 && v == proofs[i-1] {
+			continue
+		}
+
+		proof, _, err := f.ContentProof(tree, v.proofType, v.index)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to generate %v proof at %v", v.proofType, v.index)
+		}
+
+		vector.Proofs = append(vector.Proofs, *proof)
+	}
+
+	submission, err := file.NewFlow(f).CreateSubmission()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create flow submission")
+	}
+
+	for _, v := range submission.Nodes {
+		vector.Submission = append(vector.Submission, SubmissionNode{v.Root, v.Height.Uint64()})
+	}
+
+	return &vector, nil
+}
+
+// GenerateFiles generates the suite of file test vectors, where dir is used to write file content
+// temporarily.
+func GenerateFiles(dir string) (*FileVectors, error) {
+	vectors := FileVectors{
+		Comment: "Regression snapshot of this client, not cross-checked with ionian-rust. Byte i of file content is (i * seed) % 251. Regenerate with: ionian-client vectors --dir <dir>",
+	}
+
+	cases := []struct {
+		size int64
+		seed uint64
+	}{
+		{1, 7},
+		{255, 7},
+		{file.DefaultChunkSize, 7},
+		{file.DefaultChunkSize + 1, 7},
+		{17*file.DefaultChunkSize + 3, 7},
+		{1000, 0}, // all zeros
+		{file.DefaultSegmentSize - 1, 13},
+		{file.DefaultSegmentSize, 13},
+		{file.DefaultSegmentSize + 1, 13},
+		{3*file.DefaultSegmentSize + 1000, 13},
+		{17*file.DefaultSegmentSize + 5, 31}, // padded with zero segments
+	}
+
+	for _, v := range cases {
+		vector, err := GenerateFile(dir, v.size, v.seed)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to generate vector of file size %v", v.size)
+		}
+
+		vectors.Files = append(vectors.Files, *vector)
+	}
+
+	return &vectors, nil
+}
+
+// ProofIndices returns the leftmost, rightmost and middle leaves, or all leaves of a small tree.
+func ProofIndices(numLeaves int) []int {
+	if numLeaves <= 5 {
+		indices := make([]int, numLeaves)
+		for i := range indices {
+			indices[i] = i
+		}
+
+		return indices
+	}
+
+	return []int{0, 1, numLeaves / 2, numLeaves - 2, numLeaves - 1}
+}
+
+// Tree builds the merkle tree of test vector with the specified number of leaves.
+func Tree(numLeaves int) *merkle.Tree {
+	var builder merkle.TreeBuilder
+	for i := 0; i < numLeaves; i++ {
+		builder.Append(LeafContent(i))
+	}
+
+	return builder.Build()
+}
+
+// GenerateTree generates the test vector of merkle tree with the specified number of leaves.
+func GenerateTree(numLeaves int) (*TreeVector, error) {
+	tree := Tree(numLeaves)
+	vector := TreeVector{NumLeaves: numLeaves, Root: tree.Root()}
+
+	for _, i := range ProofIndices(numLeaves) {
+		proof := tree.ProofAt(i)
+
+		binary, err := proof.MarshalBinary()
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to encode proof in binary")
+		}
+
+		abi, err := proof.ABIEncode()
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to encode proof in ABI")
+		}
+
+		ssz, err := proof.MarshalSSZ()
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to encode proof in SSZ")
+		}
+
+		vector.Proofs = append(vector.Proofs, ProofVector{i, proof, binary, abi, ssz})
+	}
+
+	return &vector, nil
+}
+
+// GenerateMerkle generates the suite of merkle tree test vectors.
+func GenerateMerkle() (*MerkleVectors, error) {
+	vectors := MerkleVectors{
+		Comment: "Regression snapshot of this client, not cross-checked with ionian-rust. Leaf i is the UTF-8 string \"chunk data - i\". Regenerate with: ionian-client vectors --dir <dir>",
+	}
+
+	zeros := merkle.ZeroHashesOf(merkle.DefaultHasher, merkle.DefaultZeroLeafSize)
+	for height := 0; height <= 32; height++ {
+		vectors.ZeroHashes = append(vectors.ZeroHashes, zeros.Hash(height))
+	}
+
+	for _, numLeaves := range []int{1, 2, 3, 4, 5, 7, 8, 9, 16, 17, 31, 33} {
+		vector, err := GenerateTree(numLeaves)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to generate vector of %v leaves", numLeaves)
+		}
+
+		vectors.Trees = append(vectors.Trees, *vector)
+	}
+
+	return &vectors, nil
+}
+
+// CheckFiles checks file vectors, e.g. produced by other implementations, against this client, where
+// dir is used to write file content temporarily. Segment roots, proofs and submission are optional,
+// and only the provided ones are checked.
+func CheckFiles(dir string, vectors *FileVectors) error {
+	for _, v := range vectors.Files {
+		if err := checkFile(dir, &v); err != nil {
+			return errors.WithMessagef(err, "File vector of size %v and seed %v mismatch", v.Size, v.Seed)
+		}
+	}
+
+	return nil
+}
+
+func checkFile(dir string, vector *FileVector) error {
+	expected, err := GenerateFile(dir, vector.Size, vector.Seed)
+	if err != nil {
+		return err
+	}
+
+	if vector.NumChunks != expected.NumChunks || vector.NumPaddedChunks != expected.NumPaddedChunks {
+		return errors.Errorf("Number of chunks mismatch, expected = %v/%v, actual = %v/%v",
+			expected.NumChunks, expected.NumPaddedChunks, vector.NumChunks, vector.NumPaddedChunks)
+	}
+
+	if vector.Root != expected.Root {
+		return errors.Errorf("Root mismatch, expected = %v, actual = %v", expected.Root, vector.Root)
+	}
+
+	if len(vector.SegmentRoots) > 0 && !hashesEqual(vector.SegmentRoots, expected.SegmentRoots) {
+		return errors.New("Segment roots mismatch")
+	}
+
+	if len(vector.Submission) > 0 && !submissionEqual(vector.Submission, expected.Submission) {
+		return errors.New("Submission mismatch")
+	}
+
+	if len(vector.Proofs) == 0 {
+		return nil
+	}
+
+	filename, err := WriteFile(dir, vector.Size, vector.Seed)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+
+	f, err := file.Open(filename)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to open file")
+	}
+	defer f.Close()
+
+	tree, err := f.MerkleTree()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to create file merkle tree")
+	}
+
+	for _, proof := range vector.Proofs {
+		generated, content, err := f.ContentProof(tree, proof.Type, proof.Position)
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to generate %v proof at %v", proof.Type, proof.Position)
+		}
+
+		if err = proof.ValidatePosition(proof.Type, proof.Position, vector.Size); err == nil {
+			err = proof.Validate(vector.Root, content)
+		}
+
+		if err != nil {
+			return errors.WithMessagef(err, "Invalid %v proof at %v", proof.Type, proof.Position)
+		}
+
+		if !proofEqual(&proof.Proof, &generated.Proof) {
+			return errors.Errorf("The %v proof at %v mismatch", proof.Type, proof.Position)
+		}
+	}
+
+	return nil
+}
+
+// CheckMerkle checks merkle tree vectors, e.g. produced by other implementations, against this
+// client. Proof encodings are optional, and only the provided ones are checked.
+func CheckMerkle(vectors *MerkleVectors) error {
+	zeros := merkle.ZeroHashesOf(merkle.DefaultHasher, merkle.DefaultZeroLeafSize)
+	for height, hash := range vectors.ZeroHashes {
+		if expected := zeros.Hash(height); hash != expected {
+			return errors.Errorf("Zero hash mismatch at height %v, expected = %v, actual = %v", height, expected, hash)
+		}
+	}
+
+	for _, v := range vectors.Trees {
+		if err := checkTree(&v); err != nil {
+			return errors.WithMessagef(err, "Tree vector of %v leaves mismatch", v.NumLeaves)
+		}
+	}
+
+	return nil
+}
+
+func checkTree(vector *TreeVector) error {
+	tree := Tree(vector.NumLeaves)
+	if vector.Root != tree.Root() {
+		return errors.Errorf("Root mismatch, expected = %v, actual = %v", tree.Root(), vector.Root)
+	}
+
+	for _, v := range vector.Proofs {
+		if v.Index < 0 || v.Index >= vector.NumLeaves {
+			return errors.Errorf("Proof index out of bound %v", v.Index)
+		}
+
+		if err := v.Proof.Validate(vector.Root, LeafContent(v.Index), uint32(v.Index), uint32(vector.NumLeaves)); err != nil {
+			return errors.WithMessagef(err, "Invalid proof at %v", v.Index)
+		}
+
+		expected := tree.ProofAt(v.Index)
+		if !proofEqual(&v.Proof, &expected) {
+			return errors.Errorf("Proof at %v mismatch", v.Index)
+		}
+
+		encodings := []struct {
+			name    string
+			actual  []byte
+			marshal func() ([]byte, error)
+		}{
+			{"binary", v.Binary, expected.MarshalBinary},
+			{"ABI", v.ABI, expected.ABIEncode},
+			{"SSZ", v.SSZ, expected.MarshalSSZ},
+		}
+
+		for _, encoding := range encodings {
+			if len(encoding.actual) == 0 {
+				continue
+			}
+
+			encoded, err := encoding.marshal()
+			if err != nil {
+				return errors.WithMessagef(err, "Failed to encode proof at %v in %v", v.Index, encoding.name)
+			}
+
+			if !bytes.Equal(encoded, encoding.actual) {
+				return errors.Errorf("Proof at %v in %v mismatch", v.Index, encoding.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func hashesEqual(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func submissionEqual(a, b []SubmissionNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func proofEqual(a, b *merkle.Proof) bool {
+	if !hashesEqual(a.Lemma, b.Lemma) || len(a.Path) != len(b.Path) {
+		return false
+	}
+
+	for i := range a.Path {
+		if a.Path[i] != b.Path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write writes vectors into file in indented JSON format.
+func Write(filename string, vectors interface{}) error {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(vectors); err != nil {
+		return errors.WithMessage(err, "Failed to marshal vectors")
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// Read reads vectors from JSON file.
+func Read(filename string, vectors interface{}) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, vectors)
+}
@@ -0,0 +1,62 @@
+package vectors
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// update regenerates snapshot vectors, e.g. go test ./file/vectors -run Snapshot -update
+var update = flag.Bool("update", false, "update snapshot vectors in testdata")
+
+const (
+	fileVectorsFile   = "testdata/file.json"
+	merkleVectorsFile = "testdata/merkle.json"
+)
+
+// TestFileSnapshot checks file vectors against the checked-in snapshot generated by this client,
+// so as to detect unintended changes of roots, proofs and submissions.
+func TestFileSnapshot(t *testing.T) {
+	generated, err := GenerateFiles(t.TempDir())
+	assert.NoError(t, err)
+
+	if *update {
+		assert.NoError(t, Write(fileVectorsFile, generated))
+		return
+	}
+
+	var expected FileVectors
+	assert.NoError(t, Read(fileVectorsFile, &expected))
+	assert.Equal(t, expected, *generated)
+}
+
+// TestMerkleSnapshot checks merkle tree vectors against the checked-in snapshot generated by this
+// client, so as to detect unintended changes of roots, proofs and proof encodings.
+func TestMerkleSnapshot(t *testing.T) {
+	generated, err := GenerateMerkle()
+	assert.NoError(t, err)
+
+	if *update {
+		assert.NoError(t, Write(merkleVectorsFile, generated))
+		return
+	}
+
+	var expected MerkleVectors
+	assert.NoError(t, Read(merkleVectorsFile, &expected))
+	assert.Equal(t, expected, *generated)
+}
+
+// TestCheckMerkle checks that mismatched vectors are rejected.
+func TestCheckMerkle(t *testing.T) {
+	vectors, err := GenerateMerkle()
+	assert.NoError(t, err)
+	assert.NoError(t, CheckMerkle(vectors))
+
+	vectors.Trees[1].Proofs[0].ABI[0] ^= 1
+	assert.Error(t, CheckMerkle(vectors))
+	vectors.Trees[1].Proofs[0].ABI[0] ^= 1
+
+	vectors.Trees[1].Root[0] ^= 1
+	assert.Error(t, CheckMerkle(vectors))
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config is the configuration to start gateway server.
+type Config struct {
+	Endpoint string // address to listen on, e.g. 127.0.0.1:6789
+
+	// TLS is enabled if both cert and key files specified.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Requests should provide any of the auth tokens in header "Authorization: Bearer <token>"
+	// or "X-API-Key: <token>". Auth is disabled if not specified.
+	AuthTokens []string
+
+	MaxRequestBodySize int64         // maximum request body size in bytes, 0 for unlimited
+	ShutdownTimeout    time.Duration // maximum time to drain in-flight jobs when shutdown
+
+	LocalFileRepo     string   // root folder of local files to upload or download
+	AllowAbsolutePath bool     // whether to accept absolute file path out of the local file repository
+	AllowedOrigins    []string // origins allowed for cross-domain requests, "*" to allow all, or deny all if empty
+
+	MerkleCacheDir string // directory to cache merkle tree of local files, disabled if empty
+}
+
+// DefaultConfig returns the default configuration to start gateway server.
+func DefaultConfig() Config {
+	return Config{
+		Endpoint:           "127.0.0.1:6789",
+		MaxRequestBodySize: 1024 * 1024,
+		ShutdownTimeout:    time.Minute,
+		LocalFileRepo:      ".",
+	}
+}
+
+func (config *Config) tlsEnabled() bool {
+	return len(config.TLSCertFile) > 0 && len(config.TLSKeyFile) > 0
+}
+
+func (config *Config) allowAllOrigins() bool {
+	return len(config.AllowedOrigins) == 1 && config.AllowedOrigins[0] == "*"
+}
+
+func (config *Config) validate() error {
+	if len(config.Endpoint) == 0 {
+		return errors.New("endpoint not specified")
+	}
+
+	if (len(config.TLSCertFile) == 0) != (len(config.TLSKeyFile) == 0) {
+		return errors.New("TLS cert file and key file should be specified together")
+	}
+
+	for _, token := range config.AuthTokens {
+		if len(token) == 0 {
+			return errors.New("empty auth token")
+		}
+	}
+
+	if config.MaxRequestBodySize < 0 {
+		return errors.New("negative max request body size")
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if len(origin) == 0 {
+			return errors.New("empty allowed origin")
+		}
+
+		if origin == "*" && len(config.AllowedOrigins) > 1 {
+			return errors.New("allowed origin * should not be specified with other origins")
+		}
+	}
+
+	return nil
+}
@@ -2,9 +2,18 @@ package gateway
 
 // General errors
 var (
-	ErrNil            = newBusinessError(0, "ok")
-	ErrValidation     = newBusinessError(1, "Invalid parameter")
-	ErrInternalServer = newBusinessError(2, "Internal server error")
+	ErrNil             = newBusinessError(0, "ok")
+	ErrValidation      = newBusinessError(1, "Invalid parameter")
+	ErrInternalServer  = newBusinessError(2, "Internal server error")
+	ErrUnauthorized    = newBusinessError(3, "Unauthorized")
+	ErrRequestTooLarge = newBusinessError(4, "Request entity too large")
+	ErrServerShutdown  = newBusinessError(5, "Server is shutting down")
+)
+
+// Local file errors
+var (
+	ErrAbsolutePathNotAllowed = newBusinessError(101, "Absolute path not allowed")
+	ErrPathOutOfRepo          = newBusinessError(102, "Path out of local file repository")
 )
 
 type BusinessError struct {
@@ -1,38 +1,23 @@
 package gateway
 
 import (
-	"path/filepath"
-
 	"github.com/Ionian-Web3-Storage/ionian-client/file"
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
 
-var LocalFileRepo string = "."
-
-func listNodes(c *gin.Context) (interface{}, error) {
+func (s *Server) listNodes(c *gin.Context) (interface{}, error) {
 	var nodes []string
 
-	for _, c := range allClients {
+	for _, c := range s.clients {
 		nodes = append(nodes, c.URL())
 	}
 
 	return nodes, nil
 }
 
-func getFilePath(path string, download bool) string {
-	if filepath.IsAbs(path) {
-		return path
-	}
-
-	if !download {
-		return filepath.Join(LocalFileRepo, path)
-	}
-
-	return filepath.Join(LocalFileRepo, "download", path)
-}
-
-func getLocalFileInfo(c *gin.Context) (interface{}, error) {
+func (s *Server) getLocalFileInfo(c *gin.Context) (interface{}, error) {
 	var input struct {
 		Path string `form:"path" json:"path" binding:"required"`
 	}
@@ -41,9 +26,12 @@ func getLocalFileInfo(c *gin.Context) (interface{}, error) {
 		return nil, err
 	}
 
-	filename := getFilePath(input.Path, false)
+	filename, err := s.getFilePath(input.Path, false)
+	if err != nil {
+		return nil, err
+	}
 
-	file, err := file.Open(filename)
+	file, err := file.Open(filename, s.fileLayout())
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +50,7 @@ func getLocalFileInfo(c *gin.Context) (interface{}, error) {
 	}, nil
 }
 
-func getFileStatus(c *gin.Context) (interface{}, error) {
+func (s *Server) getFileStatus(c *gin.Context) (interface{}, error) {
 	var input struct {
 		Root string `form:"root" json:"root" binding:"required"`
 	}
@@ -75,7 +63,7 @@ func getFileStatus(c *gin.Context) (interface{}, error) {
 
 	var notFinalized bool
 
-	for _, client := range allClients {
+	for _, client := range s.clients {
 		info, err := client.GetFileInfo(root)
 		if err != nil {
 			return nil, err
@@ -98,23 +86,40 @@ func getFileStatus(c *gin.Context) (interface{}, error) {
 }
 
 // Assume that file status is `available` and not `finalized` yet.
-func uploadLocalFile(c *gin.Context) (interface{}, error) {
+func (s *Server) uploadLocalFile(c *gin.Context) (interface{}, error) {
 	var input struct {
 		Path string `form:"path" json:"path" binding:"required"`
-		Node int    `form:"node" json:"node"`
+		Node *int   `form:"node" json:"node"` // select from pool if not specified
 	}
 
 	if err := c.ShouldBind(&input); err != nil {
 		return nil, err
 	}
 
-	if input.Node < 0 || input.Node >= len(allClients) {
+	if input.Node != nil && (*input.Node < 0 || *input.Node >= len(s.clients)) {
 		return nil, ErrValidation.WithData("node index out of bound")
 	}
 
-	uploader := file.NewUploaderLight(allClients[input.Node])
+	filename, err := s.getFilePath(input.Path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := s.beginJob()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 
-	filename := getFilePath(input.Path, false)
+	option := file.DefaultUploadOption()
+	option.Layout = s.fileLayout()
+
+	var uploader *file.Uploader
+	if input.Node != nil {
+		uploader = file.NewUploaderLight(s.clients[*input.Node], option)
+	} else if uploader, err = file.NewUploaderWithPool(nil, s.pool, option); err != nil {
+		return nil, err
+	}
 
 	if err := uploader.Upload(filename); err != nil {
 		return nil, err
@@ -123,9 +128,9 @@ func uploadLocalFile(c *gin.Context) (interface{}, error) {
 	return nil, nil
 }
 
-func downloadFileLocal(c *gin.Context) (interface{}, error) {
+func (s *Server) downloadFileLocal(c *gin.Context) (interface{}, error) {
 	var input struct {
-		Node int    `form:"node" json:"node"`
+		Node *int   `form:"node" json:"node"` // select from pool if not specified
 		Root string `form:"root" json:"root" binding:"required"`
 		Path string `form:"path" json:"path" binding:"required"`
 	}
@@ -134,13 +139,32 @@ func downloadFileLocal(c *gin.Context) (interface{}, error) {
 		return nil, err
 	}
 
-	if input.Node < 0 || input.Node >= len(allClients) {
+	if input.Node != nil && (*input.Node < 0 || *input.Node >= len(s.clients)) {
 		return nil, ErrValidation.WithData("node index out of bound")
 	}
 
-	downloader := file.NewDownloader(allClients[input.Node])
+	filename, err := s.getFilePath(input.Path, true)
+	if err != nil {
+		return nil, err
+	}
 
-	filename := getFilePath(input.Path, true)
+	done, err := s.beginJob()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	option := file.DefaultDownloadOption()
+	option.Layout = s.fileLayout()
+
+	pool := s.pool
+	if input.Node != nil {
+		if pool, err = node.NewPool([]*node.Client{s.clients[*input.Node]}); err != nil {
+			return nil, err
+		}
+	}
+
+	downloader := file.NewDownloaderWithPool(pool, option)
 
 	if err := downloader.Download(input.Root, filename); err != nil {
 		return nil, err
@@ -148,3 +172,8 @@ func downloadFileLocal(c *gin.Context) (interface{}, error) {
 
 	return nil, nil
 }
+
+// fileLayout returns the default file layout with merkle tree cache dir in config.
+func (s *Server) fileLayout() file.Layout {
+	return file.Layout{MerkleCacheDir: s.config.MerkleCacheDir}
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// getFilePath resolves the specified path under the local file repository, and rejects
+// any path that escapes from the repository, including by symbolic links.
+func (s *Server) getFilePath(path string, download bool) (string, error) {
+	if filepath.IsAbs(path) {
+		if !s.config.AllowAbsolutePath {
+			return "", ErrAbsolutePathNotAllowed.WithData(path)
+		}
+
+		return filepath.Clean(path), nil
+	}
+
+	repo, err := filepath.Abs(s.config.LocalFileRepo)
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to get absolute path of local file repository")
+	}
+
+	if download {
+		repo = filepath.Join(repo, "download")
+	}
+
+	filename := filepath.Join(repo, path)
+	if !isSubPath(repo, filename) {
+		return "", ErrPathOutOfRepo.WithData(path)
+	}
+
+	// symbolic links may point to anywhere
+	resolvedRepo, err := evalExistingSymlinks(repo)
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to resolve local file repository")
+	}
+
+	resolvedFilename, err := evalExistingSymlinks(filename)
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to resolve file path")
+	}
+
+	if !isSubPath(resolvedRepo, resolvedFilename) {
+		return "", ErrPathOutOfRepo.WithData(path)
+	}
+
+	return resolvedFilename, nil
+}
+
+// isSubPath returns true if path is the same as, or under the parent folder.
+// Note, both paths should be absolute and cleaned.
+func isSubPath(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalExistingSymlinks evaluates symbolic links of the longest existing prefix of the specified
+// absolute path, so that the path of file to download could be resolved before created.
+func evalExistingSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+
+	resolvedParent, err := evalExistingSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFilePath(t *testing.T) {
+	repo := t.TempDir()
+	outside := t.TempDir()
+
+	config := DefaultConfig()
+	config.LocalFileRepo = repo
+	s := Server{config: config}
+
+	assert.NoError(t, os.Symlink(outside, filepath.Join(repo, "link")))
+
+	resolvedRepo, err := filepath.EvalSymlinks(repo)
+	assert.NoError(t, err)
+
+	// relative paths in repo
+	path, err := s.getFilePath("foo/bar.txt", false)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(resolvedRepo, "foo", "bar.txt"), path)
+
+	path, err = s.getFilePath("foo/../bar.txt", true)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(resolvedRepo, "download", "bar.txt"), path)
+
+	// path traversal
+	_, err = s.getFilePath("../bar.txt", false)
+	assert.Equal(t, ErrPathOutOfRepo.Code, err.(*BusinessError).Code)
+
+	_, err = s.getFilePath("../bar.txt", true)
+	assert.Equal(t, ErrPathOutOfRepo.Code, err.(*BusinessError).Code)
+
+	// symbolic link out of repo
+	_, err = s.getFilePath("link/bar.txt", false)
+	assert.Equal(t, ErrPathOutOfRepo.Code, err.(*BusinessError).Code)
+
+	// absolute path
+	_, err = s.getFilePath(filepath.Join(outside, "bar.txt"), false)
+	assert.Equal(t, ErrAbsolutePathNotAllowed.Code, err.(*BusinessError).Code)
+
+	s.config.AllowAbsolutePath = true
+
+	path, err = s.getFilePath(filepath.Join(outside, "bar.txt"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(outside, "bar.txt"), path)
+}
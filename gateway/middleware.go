@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	headerAuthorization = "Authorization"
+	headerApiKey        = "X-API-Key"
+	bearerPrefix        = "Bearer "
+)
+
+// middlewareAuth requires bearer token or API key in request header if auth tokens configured.
+func (s *Server) middlewareAuth() gin.HandlerFunc {
+	tokens := s.config.AuthTokens
+
+	return func(c *gin.Context) {
+		if len(tokens) == 0 || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(headerApiKey)
+		if auth := c.GetHeader(headerAuthorization); strings.HasPrefix(auth, bearerPrefix) {
+			token = strings.TrimPrefix(auth, bearerPrefix)
+		}
+
+		if !matchToken(tokens, token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchToken compares tokens in constant time to prevent timing attack.
+func matchToken(tokens []string, token string) bool {
+	if len(token) == 0 {
+		return false
+	}
+
+	var matched bool
+
+	for _, v := range tokens {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(token)) == 1 {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// middlewareBodyLimit limits the request body size if configured.
+func (s *Server) middlewareBodyLimit() gin.HandlerFunc {
+	limit := s.config.MaxRequestBodySize
+
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrRequestTooLarge)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, config Config) *Server {
+	client, err := node.NewClient("http://127.0.0.1:5678")
+	assert.NoError(t, err)
+
+	pool, err := node.NewPool([]*node.Client{client})
+	assert.NoError(t, err)
+
+	server, err := NewServer(config, pool)
+	assert.NoError(t, err)
+
+	return server
+}
+
+func serveTestRequest(server *Server, req *http.Request) int {
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestMiddlewareAuth(t *testing.T) {
+	config := DefaultConfig()
+	config.AuthTokens = []string{"foo", "bar"}
+	server := newTestServer(t, config)
+
+	req := httptest.NewRequest(http.MethodGet, "/local/nodes", nil)
+	assert.Equal(t, http.StatusUnauthorized, serveTestRequest(server, req))
+
+	req.Header.Set(headerAuthorization, "Bearer baz")
+	assert.Equal(t, http.StatusUnauthorized, serveTestRequest(server, req))
+
+	req.Header.Set(headerAuthorization, "Bearer bar")
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/local/nodes", nil)
+	req.Header.Set(headerApiKey, "foo")
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, req))
+}
+
+func TestMiddlewareBodyLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxRequestBodySize = 16
+	server := newTestServer(t, config)
+
+	req := httptest.NewRequest(http.MethodPost, "/local/upload", strings.NewReader(strings.Repeat("0", 17)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serveTestRequest(server, req))
+}
+
+func TestMiddlewareCors(t *testing.T) {
+	newRequest := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:6789/local/nodes", nil)
+		if len(origin) > 0 {
+			req.Header.Set("Origin", origin)
+		}
+		return req
+	}
+
+	// deny cross-domain requests by default
+	server := newTestServer(t, DefaultConfig())
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, newRequest("")))
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, newRequest("http://127.0.0.1:6789")))
+	assert.Equal(t, http.StatusForbidden, serveTestRequest(server, newRequest("http://evil.com")))
+
+	// allowed origins only
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"http://foo.com"}
+	server = newTestServer(t, config)
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, newRequest("http://foo.com")))
+	assert.Equal(t, http.StatusForbidden, serveTestRequest(server, newRequest("http://evil.com")))
+
+	// allow all explicitly
+	config.AllowedOrigins = []string{"*"}
+	server = newTestServer(t, config)
+	assert.Equal(t, http.StatusOK, serveTestRequest(server, newRequest("http://evil.com")))
+
+	config.AllowedOrigins = []string{"*", "http://foo.com"}
+	assert.Error(t, config.validate())
+}
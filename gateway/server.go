@@ -1,50 +1,180 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
 	"github.com/Ionian-Web3-Storage/ionian-client/node"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 const httpStatusInternalError = 600
 
-var allClients []*node.Client
+// Server is the gateway service to interact with storage nodes.
+type Server struct {
+	config  Config
+	pool    *node.Pool
+	clients []*node.Client // all storage nodes in pool
+	server  *http.Server
 
-func MustServeLocal(nodes []*node.Client) {
-	if len(nodes) == 0 {
-		logrus.Fatal("storage nodes not configured")
+	mu           sync.Mutex
+	closed       bool           // whether server is shutting down
+	jobs         sync.WaitGroup // in-flight upload or download jobs
+	shutdown     chan struct{}  // closed once shutdown completed
+	shutdownOnce sync.Once
+}
+
+func NewServer(config Config, pool *node.Pool) (*Server, error) {
+	clients := pool.Clients()
+	if len(clients) == 0 {
+		return nil, errors.New("storage nodes not configured")
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, errors.WithMessage(err, "Invalid config")
+	}
+
+	server := &Server{
+		config:   config,
+		pool:     pool,
+		clients:  clients,
+		shutdown: make(chan struct{}),
 	}
 
-	allClients = nodes
+	server.server = &http.Server{
+		Addr:    config.Endpoint,
+		Handler: server.newLocalRouter(),
+	}
+
+	return server, nil
+}
 
-	server := http.Server{
-		Addr:    "127.0.0.1:6789",
-		Handler: newLocalRouter(),
+// MustServe starts the gateway service, and shutdown gracefully on SIGINT or SIGTERM.
+func MustServe(config Config, pool *node.Pool) {
+	server, err := NewServer(config, pool)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create gateway server")
 	}
 
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-sigCh
+
+		logrus.WithField("signal", sig).Info("Begin to shutdown gateway server")
+
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to shutdown gateway server gracefully")
+		}
+	}()
+
+	if err = server.Serve(); err != nil {
 		logrus.WithError(err).Fatal("Failed to serve API")
 	}
+
+	<-server.shutdown
+
+	logrus.Info("Gateway server shutdown")
+}
+
+// Serve accepts incoming connections until server shutdown.
+func (s *Server) Serve() error {
+	logrus.WithFields(logrus.Fields{
+		"endpoint": s.config.Endpoint,
+		"tls":      s.config.tlsEnabled(),
+		"auth":     len(s.config.AuthTokens) > 0,
+	}).Info("Gateway server started")
+
+	var err error
+	if s.config.tlsEnabled() {
+		err = s.server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+	} else {
+		err = s.server.ListenAndServe()
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown stops to accept new requests and jobs, and waits for all in-flight jobs completed
+// until the specified context done. It is safe to call Shutdown more than once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	defer s.shutdownOnce.Do(func() { close(s.shutdown) })
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return errors.WithMessage(err, "Failed to shutdown HTTP server")
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.WithMessage(ctx.Err(), "Failed to drain in-flight jobs")
+	}
+}
+
+// beginJob registers an in-flight job, which should be ended via the returned func.
+func (s *Server) beginJob() (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrServerShutdown
+	}
+
+	s.jobs.Add(1)
+	metrics.GatewayActiveJobs.Inc()
+
+	return func() {
+		metrics.GatewayActiveJobs.Dec()
+		s.jobs.Done()
+	}, nil
 }
 
-func newLocalRouter() *gin.Engine {
+func (s *Server) newLocalRouter() *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery())
 	if logrus.IsLevelEnabled(logrus.DebugLevel) {
 		router.Use(gin.Logger())
 	}
-	router.Use(middlewareCors())
+	router.Use(s.middlewareCors())
+	router.Use(s.middlewareAuth())
+	router.Use(s.middlewareBodyLimit())
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	localApi := router.Group("/local")
-	localApi.GET("/nodes", wrap(listNodes))
-	localApi.GET("/file", wrap(getLocalFileInfo))
-	localApi.GET("/status", wrap(getFileStatus))
-	localApi.POST("/upload", wrap(uploadLocalFile))
-	localApi.POST("/download", wrap(downloadFileLocal))
+	localApi.GET("/nodes", wrap(s.listNodes))
+	localApi.GET("/file", wrap(s.getLocalFileInfo))
+	localApi.GET("/status", wrap(s.getFileStatus))
+	localApi.POST("/upload", wrap(s.uploadLocalFile))
+	localApi.POST("/download", wrap(s.downloadFileLocal))
 
 	return router
 }
@@ -69,11 +199,37 @@ func wrap(controller func(*gin.Context) (interface{}, error)) func(*gin.Context)
 	}
 }
 
-func middlewareCors() gin.HandlerFunc {
+func (s *Server) middlewareCors() gin.HandlerFunc {
+	// deny cross-domain requests unless origins allowed explicitly
+	if len(s.config.AllowedOrigins) == 0 {
+		return denyCrossOrigin
+	}
+
 	conf := cors.DefaultConfig()
 	conf.AllowMethods = append(conf.AllowMethods, "OPTIONS")
 	conf.AllowHeaders = append(conf.AllowHeaders, "*")
-	conf.AllowAllOrigins = true
+
+	if s.config.allowAllOrigins() {
+		conf.AllowAllOrigins = true
+	} else {
+		conf.AllowOrigins = s.config.AllowedOrigins
+	}
 
 	return cors.New(conf)
 }
+
+// denyCrossOrigin rejects requests with the Origin header of a different host.
+func denyCrossOrigin(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if len(origin) == 0 {
+		c.Next()
+		return
+	}
+
+	if u, err := url.Parse(origin); err != nil || u.Host != c.Request.Host {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	c.Next()
+}
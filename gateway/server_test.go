@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerShutdown(t *testing.T) {
+	server := newTestServer(t, DefaultConfig())
+
+	endJob, err := server.beginJob()
+	assert.NoError(t, err)
+
+	// in-flight job not drained
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, server.Shutdown(ctx))
+
+	// no more jobs accepted
+	_, err = server.beginJob()
+	assert.Equal(t, ErrServerShutdown, err)
+
+	// shutdown again, e.g. from signal handler and deferred cleanup
+	endJob()
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	select {
+	case <-server.shutdown:
+	default:
+		assert.Fail(t, "shutdown channel not closed")
+	}
+}
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminResult is the result of admin RPC on a storage node.
+type AdminResult struct {
+	Node   string      `json:"node"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// CallAdmin calls RPC on all storage nodes concurrently, and returns results in the same order of nodes.
+func CallAdmin(clients []*Client, call func(client *Client) (interface{}, error)) []AdminResult {
+	results := make([]AdminResult, len(clients))
+
+	var wg sync.WaitGroup
+
+	for i, client := range clients {
+		wg.Add(1)
+
+		go func(i int, client *Client) {
+			defer wg.Done()
+
+			results[i].Node = client.URL()
+
+			if result, err := call(client); err != nil {
+				results[i].Error = err.Error()
+			} else {
+				results[i].Result = result
+			}
+		}(i, client)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// WaitForSyncFinished polls file sync status on all storage nodes until completed, failed or RPC error.
+//
+// If timeout is positive and some storage nodes are still pending when timed out, the latest results
+// are returned along with an error, and the error message is also filled in results of pending nodes.
+func WaitForSyncFinished(clients []*Client, txSeq uint64, interval, timeout time.Duration) ([]AdminResult, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	results := make([]AdminResult, len(clients))
+	pending := make([]int, len(clients))
+	for i := range clients {
+		pending[i] = i
+	}
+
+	for {
+		var pendingClients []*Client
+		for _, i := range pending {
+			pendingClients = append(pendingClients, clients[i])
+		}
+
+		polled := CallAdmin(pendingClients, func(client *Client) (interface{}, error) {
+			return client.GetSyncStatus(txSeq)
+		})
+
+		var stillPending []int
+		for i, v := range polled {
+			index := pending[i]
+			results[index] = v
+
+			if status, ok := v.Result.(string); len(v.Error) == 0 && !(ok && IsSyncFinished(status)) {
+				stillPending = append(stillPending, index)
+			}
+		}
+
+		pending = stillPending
+
+		if len(pending) == 0 {
+			return results, nil
+		}
+
+		if !deadline.IsZero() && time.Now().Add(interval).After(deadline) {
+			err := errors.Errorf("Timeout to wait for file sync finished after %v", timeout)
+			for _, i := range pending {
+				results[i].Error = err.Error()
+			}
+
+			return results, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"txSeq":   txSeq,
+			"pending": len(pending),
+			"total":   len(clients),
+		}).Info("Wait for file sync finished")
+
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,219 @@
+package node
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNodeService simulates file sync on storage node via ionian and admin RPCs.
+type testNodeService struct {
+	mu sync.Mutex
+
+	files     map[uint64]*FileInfo // file info by tx seq
+	syncPolls map[uint64]int       // polls of sync status by tx seq, nil if sync not started
+
+	completedPolls int   // sync completed after polled for specified times, never completed if negative
+	syncFailed     bool  // sync failed once polled
+	err            error // error of all admin RPCs
+}
+
+func newTestNodeService(files ...*FileInfo) *testNodeService {
+	service := testNodeService{
+		files:     make(map[uint64]*FileInfo),
+		syncPolls: make(map[uint64]int),
+	}
+
+	for _, v := range files {
+		info := *v
+		service.files[v.Tx.Seq] = &info
+	}
+
+	return &service
+}
+
+func (s *testNodeService) GetFileInfo(root common.Hash) (*FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.files {
+		if v.Tx.DataMerkleRoot == root {
+			return v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *testNodeService) GetFileInfoByTxSeq(txSeq uint64) (*FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.files[txSeq], nil
+}
+
+func (s *testNodeService) Shutdown() (int, error) {
+	return 0, s.err
+}
+
+func (s *testNodeService) StartSyncFile(txSeq uint64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	s.syncPolls[txSeq] = 0
+
+	return 0, nil
+}
+
+func (s *testNodeService) GetSyncStatus(txSeq uint64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return "", s.err
+	}
+
+	polls, ok := s.syncPolls[txSeq]
+	if !ok {
+		return "Idle", nil
+	}
+
+	if s.syncFailed {
+		return "Failed(no peers found)", nil
+	}
+
+	polls++
+	s.syncPolls[txSeq] = polls
+
+	if s.completedPolls < 0 || polls < s.completedPolls {
+		return "Downloading", nil
+	}
+
+	if info, ok := s.files[txSeq]; ok {
+		info.Finalized = true
+	} else {
+		s.files[txSeq] = &FileInfo{Tx: Transaction{Seq: txSeq}, Finalized: true}
+	}
+
+	return SyncStatusCompleted, nil
+}
+
+func (s *testNodeService) started(txSeq uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.syncPolls[txSeq]
+	return ok
+}
+
+func newTestNodeClient(t *testing.T, service *testNodeService) *Client {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", service))
+	assert.NoError(t, server.RegisterName("admin", service))
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	// no retry for business errors
+	client, err := NewClient(httpServer.URL, ClientOption{Timeout: time.Second})
+	assert.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func TestCallAdmin(t *testing.T) {
+	failed := newTestNodeService()
+	failed.err = errors.New("admin RPC disabled")
+
+	clients := []*Client{
+		newTestNodeClient(t, newTestNodeService()),
+		newTestNodeClient(t, failed),
+		newTestNodeClient(t, newTestNodeService()),
+	}
+
+	results := CallAdmin(clients, func(client *Client) (interface{}, error) {
+		return client.Shutdown()
+	})
+
+	assert.Equal(t, 3, len(results))
+	for i, v := range results {
+		assert.Equal(t, clients[i].URL(), v.Node)
+	}
+
+	assert.Equal(t, 0, results[0].Result)
+	assert.Empty(t, results[0].Error)
+	assert.Nil(t, results[1].Result)
+	assert.Contains(t, results[1].Error, "admin RPC disabled")
+	assert.Equal(t, 0, results[2].Result)
+}
+
+func TestWaitForSyncFinished(t *testing.T) {
+	completed := newTestNodeService()
+	completed.completedPolls = 3
+	syncFailed := newTestNodeService()
+	syncFailed.syncFailed = true
+	rpcFailed := newTestNodeService()
+
+	services := []*testNodeService{completed, syncFailed, rpcFailed}
+	var clients []*Client
+	for _, v := range services {
+		clients = append(clients, newTestNodeClient(t, v))
+	}
+
+	results := CallAdmin(clients, func(client *Client) (interface{}, error) {
+		return client.StartSyncFile(5)
+	})
+	for _, v := range results {
+		assert.Empty(t, v.Error)
+	}
+
+	rpcFailed.mu.Lock()
+	rpcFailed.err = errors.New("node is shutting down")
+	rpcFailed.mu.Unlock()
+
+	results, err := WaitForSyncFinished(clients, 5, time.Millisecond, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(results))
+
+	assert.Equal(t, SyncStatusCompleted, results[0].Result)
+	assert.Equal(t, 3, completed.syncPolls[5])
+	assert.Equal(t, "Failed(no peers found)", results[1].Result)
+	assert.Contains(t, results[2].Error, "node is shutting down")
+
+	// no storage node
+	results, err = WaitForSyncFinished(nil, 5, time.Millisecond, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestWaitForSyncFinishedTimeout(t *testing.T) {
+	completed := newTestNodeService()
+	pending := newTestNodeService()
+	pending.completedPolls = -1
+
+	clients := []*Client{newTestNodeClient(t, completed), newTestNodeClient(t, pending)}
+	CallAdmin(clients, func(client *Client) (interface{}, error) {
+		return client.StartSyncFile(5)
+	})
+
+	start := time.Now()
+	results, err := WaitForSyncFinished(clients, 5, 10*time.Millisecond, 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.Equal(t, SyncStatusCompleted, results[0].Result)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "Downloading", results[1].Result)
+	assert.Equal(t, err.Error(), results[1].Error)
+}
@@ -2,18 +2,21 @@ package node
 
 import (
 	"context"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/sirupsen/logrus"
 )
 
 type Client struct {
-	url string
+	url   string
+	stats *rpcStats
 	*providers.MiddlewarableProvider
 }
 
-func MustNewClient(url string, option ...providers.Option) *Client {
+func MustNewClient(url string, option ...ClientOption) *Client {
 	client, err := NewClient(url, option...)
 	if err != nil {
 		logrus.WithError(err).WithField("url", url).Fatal("Failed to connect to storage node")
@@ -22,24 +25,32 @@ func MustNewClient(url string, option ...providers.Option) *Client {
 	return client
 }
 
-func NewClient(url string, option ...providers.Option) (*Client, error) {
-	var opt providers.Option
+// NewClient creates a client to interact with storage node, and uses the default option if not specified.
+func NewClient(url string, option ...ClientOption) (*Client, error) {
+	opt := DefaultClientOption()
 	if len(option) > 0 {
 		opt = option[0]
 	}
 
-	provider, err := providers.NewProviderWithOption(url, opt)
+	provider, err := providers.NewBaseProvider(context.Background(), url)
 	if err != nil {
 		return nil, err
 	}
 
+	opt.hookMiddlewares(provider, url)
+
+	stats := new(rpcStats)
+	provider.HookCallContext(stats.callContextMiddleware)
+	provider.HookBatchCallContext(stats.batchCallContextMiddleware)
+
 	return &Client{
 		url:                   url,
+		stats:                 stats,
 		MiddlewarableProvider: provider,
 	}, nil
 }
 
-func MustNewClients(urls []string, option ...providers.Option) []*Client {
+func MustNewClients(urls []string, option ...ClientOption) []*Client {
 	var clients []*Client
 
 	for _, url := range urls {
@@ -54,41 +65,153 @@ func (c *Client) URL() string {
 	return c.url
 }
 
+// call calls RPC on storage node, and converts the error into *RPCError if any.
+func (c *Client) call(result interface{}, method string, args ...interface{}) error {
+	err := c.MiddlewarableProvider.CallContext(context.Background(), result, method, args...)
+	return ParseError(err)
+}
+
+// batchCall calls RPCs in a JSON-RPC batch, and returns errors of each RPC converted into *RPCError.
+func (c *Client) batchCall(batch []rpc.BatchElem) ([]error, error) {
+	if err := c.MiddlewarableProvider.BatchCallContext(context.Background(), batch); err != nil {
+		return nil, ParseError(err)
+	}
+
+	errs := make([]error, len(batch))
+	for i, v := range batch {
+		errs[i] = ParseError(v.Error)
+	}
+
+	return errs, nil
+}
+
+// probe checks the status of storage node within the specified timeout and without retry.
+func (c *Client) probe(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(withoutRetry(context.Background()), timeout)
+	defer cancel()
+
+	var status Status
+	err := c.MiddlewarableProvider.CallContext(ctx, &status, "ionian_getStatus")
+	return ParseError(err)
+}
+
 // Ionian RPCs
 
 func (c *Client) GetStatus() (status Status, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &status, "ionian_getStatus")
+	err = c.call(&status, "ionian_getStatus")
 	return
 }
 
 func (c *Client) GetFileInfo(root common.Hash) (file *FileInfo, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &file, "ionian_getFileInfo", root)
+	err = c.call(&file, "ionian_getFileInfo", root)
+	return
+}
+
+func (c *Client) GetFileInfoByTxSeq(txSeq uint64) (file *FileInfo, err error) {
+	err = c.call(&file, "ionian_getFileInfoByTxSeq", txSeq)
 	return
 }
 
 func (c *Client) UploadSegment(segment SegmentWithProof) (ret int, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &ret, "ionian_uploadSegment", segment)
+	err = c.call(&ret, "ionian_uploadSegment", segment)
 	return
 }
 
 func (c *Client) DownloadSegment(root common.Hash, startIndex, endIndex uint32) (data []byte, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &data, "ionian_downloadSegment", root, startIndex, endIndex)
+	err = c.call(&data, "ionian_downloadSegment", root, startIndex, endIndex)
 	return
 }
 
+// DownloadSegmentWithProof downloads the segment of specified index along with the merkle proof in
+// file merkle tree. Returns nil if file or segment not found.
+func (c *Client) DownloadSegmentWithProof(root common.Hash, index uint32) (segment *SegmentWithProof, err error) {
+	err = c.call(&segment, "ionian_downloadSegmentWithProof", root, index)
+	return
+}
+
+// ChunkRange is the chunk index range [StartIndex, EndIndex) to download.
+type ChunkRange struct {
+	StartIndex uint32
+	EndIndex   uint32
+}
+
+// UploadSegments uploads segments in a JSON-RPC batch, and returns errors for each segment.
+func (c *Client) UploadSegments(segments []SegmentWithProof) ([]error, error) {
+	batch := make([]rpc.BatchElem, len(segments))
+	for i, v := range segments {
+		batch[i] = rpc.BatchElem{
+			Method: "ionian_uploadSegment",
+			Args:   []interface{}{v},
+			Result: new(int),
+		}
+	}
+
+	return c.batchCall(batch)
+}
+
+// DownloadSegments downloads segments of specified chunk ranges in a JSON-RPC batch, and returns
+// data and errors for each segment.
+func (c *Client) DownloadSegments(root common.Hash, ranges []ChunkRange) ([][]byte, []error, error) {
+	batch := make([]rpc.BatchElem, len(ranges))
+	for i, v := range ranges {
+		batch[i] = rpc.BatchElem{
+			Method: "ionian_downloadSegment",
+			Args:   []interface{}{root, v.StartIndex, v.EndIndex},
+			Result: new([]byte),
+		}
+	}
+
+	errs, err := c.batchCall(batch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make([][]byte, len(ranges))
+	for i, v := range batch {
+		data[i] = *v.Result.(*[]byte)
+	}
+
+	return data, errs, nil
+}
+
+// DownloadSegmentsWithProof downloads segments of specified indices along with merkle proofs in a
+// JSON-RPC batch, and returns segments and errors for each index.
+func (c *Client) DownloadSegmentsWithProof(root common.Hash, indices []uint32) ([]*SegmentWithProof, []error, error) {
+	batch := make([]rpc.BatchElem, len(indices))
+	for i, v := range indices {
+		batch[i] = rpc.BatchElem{
+			Method: "ionian_downloadSegmentWithProof",
+			Args:   []interface{}{root, v},
+			Result: new(*SegmentWithProof),
+		}
+	}
+
+	errs, err := c.batchCall(batch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	segments := make([]*SegmentWithProof, len(indices))
+	for i, v := range batch {
+		segments[i] = *v.Result.(**SegmentWithProof)
+	}
+
+	return segments, errs, nil
+}
+
 // Admin RPCs
 
 func (c *Client) Shutdown() (ret int, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &ret, "admin_shutdown")
+	err = c.call(&ret, "admin_shutdown")
 	return
 }
 
 func (c *Client) StartSyncFile(txSeq uint64) (ret int, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &ret, "admin_startSyncFile", txSeq)
+	err = c.call(&ret, "admin_startSyncFile", txSeq)
 	return
 }
 
 func (c *Client) GetSyncStatus(txSeq uint64) (status string, err error) {
-	err = c.MiddlewarableProvider.CallContext(context.Background(), &status, "admin_getSyncStatus", txSeq)
+	err = c.call(&status, "admin_getSyncStatus", txSeq)
 	return
 }
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRPCError is a JSON-RPC error with the specified code responded by test services.
+type testRPCError struct {
+	code    int
+	message string
+}
+
+func (err testRPCError) Error() string  { return err.message }
+func (err testRPCError) ErrorCode() int { return err.code }
+
+type testIonianService struct{}
+
+func (testIonianService) UploadSegment(segment SegmentWithProof) (int, error) {
+	if segment.Index == 1 {
+		return 0, testRPCError{-32602, `Invalid parameter root: "already uploaded and finalized"`}
+	}
+
+	return 0, nil
+}
+
+func (testIonianService) DownloadSegment(root common.Hash, startIndex, endIndex uint32) ([]byte, error) {
+	if startIndex >= endIndex {
+		return nil, testRPCError{-32602, `Invalid parameter end_index: "invalid chunk index range"`}
+	}
+
+	return []byte{byte(startIndex), byte(endIndex)}, nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", testIonianService{}))
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(server.Stop)
+
+	client, err := NewClient(httpServer.URL)
+	assert.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func TestUploadSegments(t *testing.T) {
+	client := newTestClient(t)
+
+	errs, err := client.UploadSegments([]SegmentWithProof{{Index: 0}, {Index: 1}, {Index: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(errs))
+	assert.NoError(t, errs[0])
+	assert.True(t, IsErrorCode(errs[1], ErrCodeFileFinalized))
+	assert.NoError(t, errs[2])
+}
+
+func TestDownloadSegments(t *testing.T) {
+	client := newTestClient(t)
+
+	data, errs, err := client.DownloadSegments(common.Hash{}, []ChunkRange{{0, 1024}, {1024, 1024}, {2048, 2050}})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{0, 0}, nil, {0, 2}}, data)
+	assert.NoError(t, errs[0])
+	assert.True(t, IsErrorCode(errs[1], ErrCodeInvalidSegment))
+	assert.NoError(t, errs[2])
+}
@@ -0,0 +1,196 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/pkg/errors"
+)
+
+// ErrorCode classifies errors of storage node RPCs.
+type ErrorCode int
+
+const (
+	ErrCodeUnknown        ErrorCode = iota
+	ErrCodeTransport                // failed to send request or receive response
+	ErrCodeTimeout                  // request timeout or cancelled
+	ErrCodeInvalidRequest           // JSON-RPC invalid request, method not found or invalid params
+	ErrCodeInternal                 // JSON-RPC internal error
+	ErrCodeFileNotFound             // file or log entry not found on storage node
+	ErrCodeFileFinalized            // file already finalized, i.e. all segments uploaded
+	ErrCodeInvalidProof             // merkle proof of segment is invalid
+	ErrCodeInvalidSegment           // segment index or data out of range
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrCodeUnknown:        "unknown",
+	ErrCodeTransport:      "transport",
+	ErrCodeTimeout:        "timeout",
+	ErrCodeInvalidRequest: "invalid request",
+	ErrCodeInternal:       "internal",
+	ErrCodeFileNotFound:   "file not found",
+	ErrCodeFileFinalized:  "file finalized",
+	ErrCodeInvalidProof:   "invalid proof",
+	ErrCodeInvalidSegment: "invalid segment",
+}
+
+func (code ErrorCode) String() string {
+	if name, ok := errorCodeNames[code]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ErrorCode(%v)", int(code))
+}
+
+// Standard JSON-RPC error codes.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCInternalError  = -32603
+)
+
+// Storage node responds business errors with JSON-RPC invalid params error, and the message is
+// formatted as `Invalid parameter {param}: "{reason}"`.
+const invalidParamPrefix = "Invalid parameter "
+
+// invalidParamErrors classifies invalid params errors by parameter name and reason. An empty
+// reason matches any reason of the parameter.
+var invalidParamErrors = []struct {
+	param  string
+	reason string
+	code   ErrorCode
+}{
+	{"root", "already uploaded and finalized", ErrCodeFileFinalized},
+	{"root", "data root not found", ErrCodeFileNotFound},
+	{"proof", "", ErrCodeInvalidProof},
+	{"index", "", ErrCodeInvalidSegment},
+	{"data", "", ErrCodeInvalidSegment},
+	{"end_index", "", ErrCodeInvalidSegment},
+}
+
+// RPCError is the typed error of storage node RPC.
+type RPCError struct {
+	Code    ErrorCode
+	RPCCode int         // JSON-RPC error code, 0 if not responded by storage node
+	Message string      // JSON-RPC error message, or the underlying error message
+	Data    interface{} // JSON-RPC error data
+
+	cause error
+}
+
+func (err *RPCError) Error() string {
+	return err.cause.Error()
+}
+
+// Cause returns the underlying error, which is compatible with github.com/pkg/errors.
+func (err *RPCError) Cause() error {
+	return err.cause
+}
+
+// Unwrap returns the underlying error, which is compatible with the standard errors package.
+func (err *RPCError) Unwrap() error {
+	return err.cause
+}
+
+// Retryable returns whether the RPC could succeed if retried later or requested to another storage node.
+// Unknown errors are not retryable, since the RPC may be not idempotent.
+func (err *RPCError) Retryable() bool {
+	switch err.Code {
+	case ErrCodeTransport, ErrCodeTimeout, ErrCodeInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseError converts the error returned by storage node RPC into *RPCError. Returns nil if the
+// specified error is nil.
+func ParseError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return err
+	}
+
+	var jsonErr *rpc.JsonError
+	if errors.As(err, &jsonErr) {
+		return &RPCError{
+			Code:    classifyJSONError(jsonErr),
+			RPCCode: jsonErr.Code,
+			Message: jsonErr.Message,
+			Data:    jsonErr.Data,
+			cause:   err,
+		}
+	}
+
+	code := ErrCodeTransport
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		code = ErrCodeTimeout
+	}
+
+	return &RPCError{
+		Code:    code,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+func classifyJSONError(err *rpc.JsonError) ErrorCode {
+	switch err.Code {
+	case jsonRPCParseError, jsonRPCInvalidRequest, jsonRPCMethodNotFound:
+		return ErrCodeInvalidRequest
+	case jsonRPCInvalidParams:
+		return classifyInvalidParams(err.Message)
+	case jsonRPCInternalError:
+		return ErrCodeInternal
+	default:
+		return ErrCodeUnknown
+	}
+}
+
+// classifyInvalidParams classifies invalid params error by the formatted message, and returns
+// ErrCodeInvalidRequest for unknown parameter or reason.
+func classifyInvalidParams(message string) ErrorCode {
+	if !strings.HasPrefix(message, invalidParamPrefix) {
+		return ErrCodeInvalidRequest
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(message, invalidParamPrefix), ": ", 2)
+	if len(fields) != 2 {
+		return ErrCodeInvalidRequest
+	}
+
+	reason, err := strconv.Unquote(fields[1])
+	if err != nil {
+		reason = fields[1]
+	}
+
+	for _, v := range invalidParamErrors {
+		if v.param == fields[0] && (len(v.reason) == 0 || v.reason == reason) {
+			return v.code
+		}
+	}
+
+	return ErrCodeInvalidRequest
+}
+
+// IsErrorCode returns whether the specified error is an *RPCError with the specified code.
+func IsErrorCode(err error, code ErrorCode) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
+
+// IsRetryable returns whether the specified error is retryable. Note, errors that not returned
+// by storage node RPC are not retryable.
+func IsRetryable(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Retryable()
+}
@@ -0,0 +1,48 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	assert.Nil(t, ParseError(nil))
+
+	cases := []struct {
+		err       error
+		code      ErrorCode
+		retryable bool
+	}{
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter root: "already uploaded and finalized"`}, ErrCodeFileFinalized, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter root: "data root not found"`}, ErrCodeFileNotFound, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter proof: "validation failed"`}, ErrCodeInvalidProof, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter index: "segment index out of range"`}, ErrCodeInvalidSegment, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter data: "invalid data length"`}, ErrCodeInvalidSegment, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter end_index: "invalid chunk index range"`}, ErrCodeInvalidSegment, false},
+		{&rpc.JsonError{Code: -32602, Message: `Invalid parameter root: "invalid hex"`}, ErrCodeInvalidRequest, false},
+		{&rpc.JsonError{Code: -32602, Message: "Invalid params"}, ErrCodeInvalidRequest, false},
+		{&rpc.JsonError{Code: -32700, Message: "Parse error"}, ErrCodeInvalidRequest, false},
+		{&rpc.JsonError{Code: -32600, Message: "Invalid request"}, ErrCodeInvalidRequest, false},
+		{&rpc.JsonError{Code: -32601, Message: "Method not found"}, ErrCodeInvalidRequest, false},
+		{&rpc.JsonError{Code: -32603, Message: `Internal error: "database not available"`}, ErrCodeInternal, true},
+		{&rpc.JsonError{Code: -32000, Message: "already uploaded"}, ErrCodeUnknown, false},
+		{errors.WithMessage(context.DeadlineExceeded, "Failed after 3 retries"), ErrCodeTimeout, true},
+		{errors.New("connection refused"), ErrCodeTransport, true},
+	}
+
+	for _, v := range cases {
+		err := ParseError(v.err)
+		assert.True(t, IsErrorCode(err, v.code), "%v: %v", v.err, err.(*RPCError).Code)
+		assert.Equal(t, v.retryable, IsRetryable(err))
+		assert.Equal(t, v.err.Error(), err.Error())
+		assert.Equal(t, errors.Cause(v.err), errors.Cause(err))
+	}
+
+	// parse again
+	err := ParseError(&rpc.JsonError{Code: -32603, Message: "Internal error"})
+	assert.Equal(t, err, ParseError(err))
+}
@@ -0,0 +1,271 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ionian-Web3-Storage/ionian-client/common/metrics"
+	"github.com/openweb3/go-rpc-provider"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/openweb3/go-rpc-provider/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// idempotentMethods could be retried safely when failed to call.
+var idempotentMethods = map[string]bool{
+	"ionian_getStatus":                true,
+	"ionian_getFileInfo":              true,
+	"ionian_getFileInfoByTxSeq":       true,
+	"ionian_downloadSegment":          true,
+	"ionian_downloadSegmentWithProof": true,
+	"admin_getSyncStatus":             true,
+}
+
+// ClientOption is the option to create storage node client.
+type ClientOption struct {
+	Timeout time.Duration // timeout for each RPC call, 0 for no timeout
+
+	// Retry with exponential backoff for idempotent RPCs.
+	MaxRetries       int
+	RetryInterval    time.Duration // backoff before the first retry, doubled for subsequent retries
+	MaxRetryInterval time.Duration // upper bound of backoff, 0 for unlimited
+
+	// Token bucket rate limit of RPC calls to storage node.
+	RateLimit float64 // requests per second, 0 for unlimited
+	RateBurst int     // maximum burst requests
+}
+
+// DefaultClientOption returns the default option to create storage node client.
+func DefaultClientOption() ClientOption {
+	return ClientOption{
+		Timeout:          30 * time.Second,
+		MaxRetries:       3,
+		RetryInterval:    time.Second,
+		MaxRetryInterval: 10 * time.Second,
+	}
+}
+
+// hookMiddlewares hooks all middlewares in order: log -> retry -> rate limit -> timeout -> metrics.
+func (option *ClientOption) hookMiddlewares(provider *providers.MiddlewarableProvider, url string) {
+	provider.HookCallContext(logMiddleware(url))
+	provider.HookBatchCallContext(logBatchMiddleware(url))
+
+	if option.MaxRetries > 0 {
+		provider.HookCallContext(option.retryMiddleware)
+		provider.HookBatchCallContext(option.retryBatchMiddleware)
+	}
+
+	if option.RateLimit > 0 {
+		limiter := newRateLimiter(option.RateLimit, option.RateBurst)
+		provider.HookCallContext(limiter.callContextMiddleware)
+		provider.HookBatchCallContext(limiter.batchCallContextMiddleware)
+	}
+
+	if option.Timeout > 0 {
+		timeout := providers.TimeoutMiddleware{Timeout: option.Timeout}
+		provider.HookCallContext(timeout.CallContext)
+		provider.HookBatchCallContext(timeout.BatchCallContext)
+	}
+
+	provider.HookCallContext(metricsMiddleware(url))
+	provider.HookBatchCallContext(metricsBatchMiddleware(url))
+}
+
+// logMiddleware logs RPC requests and responses in trace level.
+func logMiddleware(url string) providers.CallContextMiddleware {
+	return func(call providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if !logrus.IsLevelEnabled(logrus.TraceLevel) {
+				return call(ctx, result, method, args...)
+			}
+
+			start := time.Now()
+			err := call(ctx, result, method, args...)
+
+			logger := logrus.WithFields(logrus.Fields{
+				"node":    url,
+				"method":  method,
+				"args":    args,
+				"elapsed": time.Since(start),
+			})
+
+			if err != nil {
+				logger.WithError(err).Trace("Failed to call RPC")
+			} else {
+				logger.WithField("result", result).Trace("Succeeded to call RPC")
+			}
+
+			return err
+		}
+	}
+}
+
+func logBatchMiddleware(url string) providers.BatchCallContextMiddleware {
+	return func(call providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+		return func(ctx context.Context, b []rpc.BatchElem) error {
+			if !logrus.IsLevelEnabled(logrus.TraceLevel) {
+				return call(ctx, b)
+			}
+
+			start := time.Now()
+			err := call(ctx, b)
+
+			logger := logrus.WithFields(logrus.Fields{
+				"node":    url,
+				"batch":   len(b),
+				"elapsed": time.Since(start),
+			})
+
+			if err != nil {
+				logger.WithError(err).Trace("Failed to batch call RPC")
+			} else {
+				logger.Trace("Succeeded to batch call RPC")
+			}
+
+			return err
+		}
+	}
+}
+
+// noRetryKey is the context key to disable retry for RPC, e.g. health check.
+type noRetryKey struct{}
+
+// withoutRetry returns a context to call RPC without retry.
+func withoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func (option *ClientOption) retryMiddleware(call providers.CallContextFunc) providers.CallContextFunc {
+	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		if !idempotentMethods[method] || ctx.Value(noRetryKey{}) != nil {
+			return call(ctx, result, method, args...)
+		}
+
+		return option.retry(ctx, method, func() error {
+			return call(ctx, result, method, args...)
+		})
+	}
+}
+
+func (option *ClientOption) retryBatchMiddleware(call providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+	return func(ctx context.Context, b []rpc.BatchElem) error {
+		for _, elem := range b {
+			if !idempotentMethods[elem.Method] {
+				return call(ctx, b)
+			}
+		}
+
+		return option.retry(ctx, "batch", func() error {
+			return call(ctx, b)
+		})
+	}
+}
+
+// retry retries the handler with exponential backoff, unless JSON-RPC error responded by storage node.
+func (option *ClientOption) retry(ctx context.Context, method string, handler func() error) error {
+	interval := option.RetryInterval
+
+	for i := 0; ; i++ {
+		err := handler()
+		if err == nil || utils.IsRPCJSONError(err) {
+			return err
+		}
+
+		if i >= option.MaxRetries {
+			return errors.WithMessagef(err, "Failed after %v retries", option.MaxRetries)
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"method":   method,
+			"retry":    i + 1,
+			"interval": interval,
+		}).Debug("Failed to call RPC, retry later")
+
+		select {
+		case <-ctx.Done():
+			return errors.WithMessage(ctx.Err(), err.Error())
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if option.MaxRetryInterval > 0 && interval > option.MaxRetryInterval {
+			interval = option.MaxRetryInterval
+		}
+	}
+}
+
+type rateLimiter struct {
+	limiter *rate.Limiter
+}
+
+func newRateLimiter(limit float64, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &rateLimiter{rate.NewLimiter(rate.Limit(limit), burst)}
+}
+
+func (l *rateLimiter) callContextMiddleware(call providers.CallContextFunc) providers.CallContextFunc {
+	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return errors.WithMessage(err, "Failed to wait for rate limit")
+		}
+
+		return call(ctx, result, method, args...)
+	}
+}
+
+// batchCallContextMiddleware consumes one token for each request in batch.
+func (l *rateLimiter) batchCallContextMiddleware(call providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+	return func(ctx context.Context, b []rpc.BatchElem) error {
+		for range b {
+			if err := l.limiter.Wait(ctx); err != nil {
+				return errors.WithMessage(err, "Failed to wait for rate limit")
+			}
+		}
+
+		return call(ctx, b)
+	}
+}
+
+// metricsMiddleware collects latency and errors of RPCs for the specified storage node.
+func metricsMiddleware(url string) providers.CallContextMiddleware {
+	return func(call providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			start := time.Now()
+			err := call(ctx, result, method, args...)
+			metrics.RPCDuration.WithLabelValues(url, method).Observe(metrics.Since(start))
+			if err != nil {
+				metrics.RPCErrors.WithLabelValues(url, method).Inc()
+			}
+
+			return err
+		}
+	}
+}
+
+// metricsBatchMiddleware collects latency and errors of batch RPCs, which uses the method of the first
+// request with suffix "_batch" as label.
+func metricsBatchMiddleware(url string) providers.BatchCallContextMiddleware {
+	return func(call providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+		return func(ctx context.Context, b []rpc.BatchElem) error {
+			if len(b) == 0 {
+				return call(ctx, b)
+			}
+
+			method := b[0].Method + "_batch"
+
+			start := time.Now()
+			err := call(ctx, b)
+			metrics.RPCDuration.WithLabelValues(url, method).Observe(metrics.Since(start))
+			if err != nil {
+				metrics.RPCErrors.WithLabelValues(url, method).Inc()
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryMiddleware(t *testing.T) {
+	option := ClientOption{
+		MaxRetries:    3,
+		RetryInterval: time.Millisecond,
+	}
+
+	var calls int
+	call := option.retryMiddleware(func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		calls++
+		return errors.New("transport error")
+	})
+
+	// retry idempotent RPC
+	assert.Error(t, call(context.Background(), nil, "ionian_getFileInfo"))
+	assert.Equal(t, 4, calls)
+
+	// never retry non-idempotent RPC
+	calls = 0
+	assert.Error(t, call(context.Background(), nil, "ionian_uploadSegment"))
+	assert.Equal(t, 1, calls)
+
+	// stop retry once context cancelled
+	calls = 0
+	option.RetryInterval = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, call(ctx, nil, "ionian_downloadSegment"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(100, 1)
+	call := limiter.callContextMiddleware(func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		return nil
+	})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, call(context.Background(), nil, "ionian_getStatus"))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
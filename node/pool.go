@@ -0,0 +1,327 @@
+package node
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultLatency is used to score node that has not been requested yet.
+	defaultLatency = 100 * time.Millisecond
+
+	// minSuccessRate is used to score node that always failed, so as to be selected with low probability.
+	minSuccessRate = 0.01
+)
+
+// PoolOption is the option to manage storage nodes in pool.
+type PoolOption struct {
+	HealthCheckInterval time.Duration // interval to check node health via GetStatus
+	HealthCheckTimeout  time.Duration // timeout to check health of a node, which is never retried
+	MaxErrorRate        float64       // node will be evicted if error rate exceeds, in range (0, 1]
+}
+
+// DefaultPoolOption returns the default option to manage storage nodes in pool.
+func DefaultPoolOption() PoolOption {
+	return PoolOption{
+		HealthCheckInterval: 10 * time.Second,
+		HealthCheckTimeout:  3 * time.Second,
+		MaxErrorRate:        0.5,
+	}
+}
+
+// NodeStats is the statistics of a storage node in pool.
+type NodeStats struct {
+	URL       string        `json:"url"`
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"latency"`   // EWMA of RPC latency
+	ErrorRate float64       `json:"errorRate"` // EWMA of RPC failures
+}
+
+func (stats *NodeStats) weight() float64 {
+	latency := stats.Latency
+	if latency <= 0 {
+		latency = defaultLatency
+	}
+
+	successRate := 1 - stats.ErrorRate
+	if successRate < minSuccessRate {
+		successRate = minSuccessRate
+	}
+
+	return successRate / latency.Seconds()
+}
+
+type poolNode struct {
+	client  *Client
+	healthy bool
+}
+
+func (node *poolNode) stats() NodeStats {
+	latency, errorRate := node.client.stats.get()
+
+	return NodeStats{
+		URL:       node.client.URL(),
+		Healthy:   node.healthy,
+		Latency:   latency,
+		ErrorRate: errorRate,
+	}
+}
+
+// Pool manages a set of storage nodes, and selects nodes weighted toward faster ones according to
+// the latency and error rate of RPCs. Once started, nodes are checked periodically,
+// and evicted or re-added automatically according to the health status.
+type Pool struct {
+	option PoolOption
+	nodes  []*poolNode
+	mu     sync.RWMutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// poolOptionOrDefault returns the pool option with zero values replaced by defaults. Invalid values,
+// e.g. negative interval, are kept as they are so as to be rejected by validate.
+func poolOptionOrDefault(option ...PoolOption) PoolOption {
+	var opt PoolOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	defaultOpt := DefaultPoolOption()
+
+	if opt.HealthCheckInterval == 0 {
+		opt.HealthCheckInterval = defaultOpt.HealthCheckInterval
+	}
+
+	if opt.HealthCheckTimeout == 0 {
+		opt.HealthCheckTimeout = defaultOpt.HealthCheckTimeout
+	}
+
+	if opt.MaxErrorRate == 0 {
+		opt.MaxErrorRate = defaultOpt.MaxErrorRate
+	}
+
+	return opt
+}
+
+func (option *PoolOption) validate() error {
+	if option.HealthCheckInterval < 0 {
+		return errors.Errorf("Invalid health check interval %v", option.HealthCheckInterval)
+	}
+
+	if option.HealthCheckTimeout < 0 {
+		return errors.Errorf("Invalid health check timeout %v", option.HealthCheckTimeout)
+	}
+
+	if option.MaxErrorRate <= 0 || option.MaxErrorRate > 1 {
+		return errors.Errorf("Invalid max error rate %v, should be in range (0, 1]", option.MaxErrorRate)
+	}
+
+	return nil
+}
+
+// NewPool creates a pool with specified storage nodes. Zero values of option are replaced by defaults,
+// and out-of-range values are rejected.
+func NewPool(clients []*Client, option ...PoolOption) (*Pool, error) {
+	opt := poolOptionOrDefault(option...)
+	if err := opt.validate(); err != nil {
+		return nil, errors.WithMessage(err, "Invalid pool option")
+	}
+
+	pool := &Pool{option: opt}
+
+	for _, client := range clients {
+		pool.nodes = append(pool.nodes, &poolNode{client, true})
+	}
+
+	return pool, nil
+}
+
+// MustNewPool creates clients for the specified URLs and a pool to manage them.
+func MustNewPool(urls []string, clientOption ClientOption, poolOption PoolOption) *Pool {
+	pool, err := NewPool(MustNewClients(urls, clientOption), poolOption)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create storage node pool")
+	}
+
+	return pool
+}
+
+// Start checks health of all storage nodes once, which takes HealthCheckTimeout at most, and then
+// periodically in background.
+func (pool *Pool) Start() {
+	pool.checkHealth()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.cancel = cancel
+
+	pool.wg.Add(1)
+	go func() {
+		defer pool.wg.Done()
+
+		ticker := time.NewTicker(pool.option.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pool.checkHealth()
+			}
+		}
+	}()
+}
+
+// Close stops the background health check, and closes all clients.
+func (pool *Pool) Close() {
+	if pool.cancel != nil {
+		pool.cancel()
+		pool.wg.Wait()
+	}
+
+	for _, node := range pool.nodes {
+		node.client.Close()
+	}
+}
+
+// checkHealth checks health of all storage nodes concurrently.
+func (pool *Pool) checkHealth() {
+	errs := make([]error, len(pool.nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range pool.nodes {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			errs[i] = client.probe(pool.option.HealthCheckTimeout)
+		}(i, node.client)
+	}
+	wg.Wait()
+
+	for i, node := range pool.nodes {
+		pool.mu.Lock()
+		_, errorRate := node.client.stats.get()
+		healthy := errs[i] == nil && errorRate <= pool.option.MaxErrorRate
+		changed := healthy != node.healthy
+		node.healthy = healthy
+		stats := node.stats()
+		pool.mu.Unlock()
+
+		if !changed {
+			continue
+		}
+
+		if healthy {
+			logrus.WithField("stats", stats).Info("Storage node re-added into pool")
+		} else {
+			logrus.WithError(errs[i]).WithField("stats", stats).Warn("Storage node evicted from pool")
+		}
+	}
+}
+
+// Clients returns all storage nodes in pool, including the evicted ones.
+func (pool *Pool) Clients() []*Client {
+	var clients []*Client
+
+	for _, node := range pool.nodes {
+		clients = append(clients, node.client)
+	}
+
+	return clients
+}
+
+// Healthy returns all healthy storage nodes in pool, ordered by score in descending order.
+func (pool *Pool) Healthy() []*Client {
+	nodes, weights := pool.healthyNodes()
+
+	sort.Sort(byWeight{nodes, weights})
+
+	var clients []*Client
+	for _, node := range nodes {
+		clients = append(clients, node.client)
+	}
+
+	return clients
+}
+
+// Select randomly selects a healthy storage node weighted by score, which prefers nodes with lower
+// latency and error rate, and the excluded nodes are never selected, e.g. nodes failed already.
+// Returns nil if no healthy node available.
+func (pool *Pool) Select(excluded ...*Client) *Client {
+	nodes, weights := pool.healthyNodes(excluded...)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+
+	target := rand.Float64() * total
+
+	for i, weight := range weights {
+		if target -= weight; target < 0 {
+			return nodes[i].client
+		}
+	}
+
+	return nodes[len(nodes)-1].client
+}
+
+func (pool *Pool) healthyNodes(excluded ...*Client) (nodes []*poolNode, weights []float64) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	for _, node := range pool.nodes {
+		if node.healthy && !containsClient(excluded, node.client) {
+			stats := node.stats()
+			nodes = append(nodes, node)
+			weights = append(weights, stats.weight())
+		}
+	}
+
+	return
+}
+
+func containsClient(clients []*Client, client *Client) bool {
+	for _, v := range clients {
+		if v == client {
+			return true
+		}
+	}
+
+	return false
+}
+
+type byWeight struct {
+	nodes   []*poolNode
+	weights []float64
+}
+
+func (s byWeight) Len() int           { return len(s.nodes) }
+func (s byWeight) Less(i, j int) bool { return s.weights[i] > s.weights[j] }
+func (s byWeight) Swap(i, j int) {
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.weights[i], s.weights[j] = s.weights[j], s.weights[i]
+}
+
+// Stats returns statistics of all storage nodes in pool.
+func (pool *Pool) Stats() []NodeStats {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var stats []NodeStats
+	for _, node := range pool.nodes {
+		stats = append(stats, node.stats())
+	}
+
+	return stats
+}
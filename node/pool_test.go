@@ -0,0 +1,156 @@
+package node
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUnreachableURL returns the URL of a closed HTTP server, so that RPCs always fail.
+func newUnreachableURL() string {
+	httpServer := httptest.NewServer(nil)
+	httpServer.Close()
+
+	return httpServer.URL
+}
+
+func newTestPool(t *testing.T, latencies ...time.Duration) *Pool {
+	var clients []*Client
+
+	for _, latency := range latencies {
+		client, err := NewClient(newUnreachableURL(), ClientOption{Timeout: time.Second})
+		assert.NoError(t, err)
+		client.stats.record(latency, nil)
+		clients = append(clients, client)
+	}
+
+	pool, err := NewPool(clients)
+	assert.NoError(t, err)
+
+	return pool
+}
+
+func TestNewPool(t *testing.T) {
+	pool, err := NewPool(nil, PoolOption{MaxErrorRate: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, PoolOption{
+		HealthCheckInterval: DefaultPoolOption().HealthCheckInterval,
+		HealthCheckTimeout:  DefaultPoolOption().HealthCheckTimeout,
+		MaxErrorRate:        1,
+	}, pool.option)
+
+	for _, rate := range []float64{-0.1, 1.1} {
+		_, err = NewPool(nil, PoolOption{MaxErrorRate: rate})
+		assert.Error(t, err)
+	}
+
+	_, err = NewPool(nil, PoolOption{HealthCheckInterval: -time.Second})
+	assert.Error(t, err)
+
+	_, err = NewPool(nil, PoolOption{HealthCheckTimeout: -time.Second})
+	assert.Error(t, err)
+}
+
+func TestPoolSelect(t *testing.T) {
+	pool := newTestPool(t, 10*time.Millisecond, 90*time.Millisecond)
+	fast := pool.Clients()[0]
+
+	var selected int
+	for i := 0; i < 1000; i++ {
+		if pool.Select() == fast {
+			selected++
+		}
+	}
+
+	// 90% in theory
+	assert.Greater(t, selected, 800)
+	assert.Equal(t, fast, pool.Healthy()[0])
+}
+
+func TestPoolEviction(t *testing.T) {
+	pool := newTestPool(t, 10*time.Millisecond, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		pool.Clients()[0].stats.record(0, errors.New("transport error"))
+	}
+
+	// node unreachable and evicted
+	pool.checkHealth()
+	assert.Empty(t, pool.Healthy())
+	assert.Nil(t, pool.Select())
+
+	stats := pool.Stats()
+	assert.Equal(t, 2, len(stats))
+	assert.False(t, stats[0].Healthy)
+	assert.Greater(t, stats[0].ErrorRate, stats[1].ErrorRate)
+}
+
+type testStatusService struct {
+	delay time.Duration
+}
+
+func (s *testStatusService) GetStatus() (Status, error) {
+	time.Sleep(s.delay)
+	return Status{}, nil
+}
+
+func newTestStatusClient(t *testing.T, delay time.Duration) *Client {
+	server := rpc.NewServer()
+	assert.NoError(t, server.RegisterName("ionian", &testStatusService{delay}))
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	// health check should never retry or wait for the client timeout
+	client, err := NewClient(httpServer.URL, DefaultClientOption())
+	assert.NoError(t, err)
+
+	return client
+}
+
+func TestPoolHealthCheckTimeout(t *testing.T) {
+	pool, err := NewPool([]*Client{
+		newTestStatusClient(t, 0),
+		newTestStatusClient(t, time.Second),
+		newTestStatusClient(t, time.Second),
+	}, PoolOption{HealthCheckInterval: time.Minute, HealthCheckTimeout: 100 * time.Millisecond, MaxErrorRate: 1})
+	assert.NoError(t, err)
+
+	// slow nodes are checked concurrently
+	start := time.Now()
+	pool.checkHealth()
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+
+	healthy := pool.Healthy()
+	assert.Equal(t, 1, len(healthy))
+	assert.Equal(t, pool.Clients()[0], healthy[0])
+}
+
+func TestPoolSelectExcluded(t *testing.T) {
+	pool := newTestPool(t, 10*time.Millisecond, 90*time.Millisecond)
+	fast, slow := pool.Clients()[0], pool.Clients()[1]
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, slow, pool.Select(fast))
+	}
+
+	assert.Nil(t, pool.Select(fast, slow))
+}
+
+func TestBatchStats(t *testing.T) {
+	client := newTestClient(t)
+	client.stats.record(10*time.Millisecond, nil)
+
+	// batch RPC only affects error rate
+	_, _, err := client.DownloadSegments(common.Hash{}, []ChunkRange{{0, 1}, {1, 2}})
+	assert.NoError(t, err)
+
+	latency, errorRate := client.stats.get()
+	assert.Equal(t, 10*time.Millisecond, latency)
+	assert.Equal(t, float64(0), errorRate)
+}
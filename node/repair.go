@@ -0,0 +1,229 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// File state on storage node before repair.
+const (
+	FileStateMissing      = "missing"
+	FileStateUnfinalized  = "unfinalized"
+	FileStateFinalized    = "finalized"
+	FileStateQueryFailure = "unknown"
+)
+
+// RepairItem is the repair status of a file on a storage node.
+type RepairItem struct {
+	TxSeq      *uint64      `json:"txSeq,omitempty"` // nil if file not found on any storage node
+	Root       *common.Hash `json:"root,omitempty"`  // nil if file not found on any storage node
+	Node       string       `json:"node"`
+	State      string       `json:"state"`                // file state before repair
+	SyncStatus string       `json:"syncStatus,omitempty"` // latest sync status if sync triggered
+	Error      string       `json:"error,omitempty"`
+}
+
+// Repaired returns whether the file is available and finalized, or sync completed on storage node.
+func (item *RepairItem) Repaired() bool {
+	if len(item.Error) > 0 {
+		return false
+	}
+
+	return item.State == FileStateFinalized || IsSyncCompleted(item.SyncStatus)
+}
+
+// RepairOption is the option to repair files on storage nodes.
+type RepairOption struct {
+	PollInterval time.Duration // interval to poll file sync status
+	Timeout      time.Duration // maximum time to wait for all file sync finished, 0 for no limit
+}
+
+// DefaultRepairOption returns the default option to repair files on storage nodes.
+func DefaultRepairOption() RepairOption {
+	return RepairOption{
+		PollInterval: 3 * time.Second,
+	}
+}
+
+// Repairer detects files that missed or not finalized on storage nodes, and triggers file sync on them.
+type Repairer struct {
+	clients map[string]*Client // storage node URL -> client
+	urls    []string           // storage node URLs in order
+	option  RepairOption
+}
+
+// NewRepairer creates a repairer for the specified storage nodes, which should have different URLs.
+func NewRepairer(clients []*Client, option ...RepairOption) (*Repairer, error) {
+	opt := DefaultRepairOption()
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if opt.PollInterval <= 0 {
+		return nil, errors.Errorf("Invalid poll interval %v", opt.PollInterval)
+	}
+
+	repairer := Repairer{
+		clients: make(map[string]*Client),
+		option:  opt,
+	}
+
+	for _, client := range clients {
+		if _, ok := repairer.clients[client.URL()]; ok {
+			return nil, errors.Errorf("Duplicate storage node %v", client.URL())
+		}
+
+		repairer.clients[client.URL()] = client
+		repairer.urls = append(repairer.urls, client.URL())
+	}
+
+	return &repairer, nil
+}
+
+// RepairByRoots repairs files of specified merkle roots on all storage nodes.
+func (repairer *Repairer) RepairByRoots(roots []common.Hash) []*RepairItem {
+	var items []*RepairItem
+
+	for _, root := range roots {
+		items = append(items, repairer.detect(func(client *Client) (*FileInfo, error) {
+			return client.GetFileInfo(root)
+		})...)
+	}
+
+	return repairer.repair(items)
+}
+
+// RepairByTxSeqs repairs files of specified transaction sequence range [start, end] on all storage nodes.
+func (repairer *Repairer) RepairByTxSeqs(start, end uint64) []*RepairItem {
+	var items []*RepairItem
+
+	for txSeq := start; txSeq <= end; txSeq++ {
+		seq := txSeq
+		fileItems := repairer.detect(func(client *Client) (*FileInfo, error) {
+			return client.GetFileInfoByTxSeq(seq)
+		})
+
+		// file sync could be triggered by tx seq even not found on any storage node
+		for _, v := range fileItems {
+			v.TxSeq = &seq
+		}
+
+		items = append(items, fileItems...)
+	}
+
+	return repairer.repair(items)
+}
+
+// detect queries file info on all storage nodes, and fills the tx seq and root for all items
+// if found on any storage node.
+func (repairer *Repairer) detect(query func(client *Client) (*FileInfo, error)) []*RepairItem {
+	var items []*RepairItem
+	var found *FileInfo
+
+	for _, url := range repairer.urls {
+		client := repairer.clients[url]
+		item := RepairItem{Node: url}
+
+		info, err := query(client)
+		if err != nil {
+			item.State = FileStateQueryFailure
+			item.Error = errors.WithMessage(err, "Failed to query file info").Error()
+		} else if info == nil {
+			item.State = FileStateMissing
+		} else if !info.Finalized {
+			item.State = FileStateUnfinalized
+			found = info
+		} else {
+			item.State = FileStateFinalized
+			found = info
+		}
+
+		items = append(items, &item)
+	}
+
+	if found != nil {
+		for _, v := range items {
+			v.TxSeq = &found.Tx.Seq
+			v.Root = &found.Tx.DataMerkleRoot
+		}
+	}
+
+	return items
+}
+
+// repair triggers file sync on storage nodes that missed file or not finalized, and waits for
+// all sync completed, failed or timed out.
+func (repairer *Repairer) repair(items []*RepairItem) []*RepairItem {
+	var pending []*RepairItem
+	clients := repairer.clients
+
+	for _, v := range items {
+		if v.State != FileStateMissing && v.State != FileStateUnfinalized {
+			continue
+		}
+
+		if v.TxSeq == nil {
+			v.Error = "File not found on any storage node"
+			continue
+		}
+
+		if _, err := clients[v.Node].StartSyncFile(*v.TxSeq); err != nil {
+			v.Error = errors.WithMessage(err, "Failed to start to sync file").Error()
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"txSeq": *v.TxSeq,
+			"node":  v.Node,
+			"state": v.State,
+		}).Info("Started to sync file")
+
+		pending = append(pending, v)
+	}
+
+	var deadline time.Time
+	if repairer.option.Timeout > 0 {
+		deadline = time.Now().Add(repairer.option.Timeout)
+	}
+
+	for len(pending) > 0 {
+		if !deadline.IsZero() && time.Now().Add(repairer.option.PollInterval).After(deadline) {
+			for _, v := range pending {
+				v.Error = fmt.Sprintf("Timeout to wait for file sync finished after %v", repairer.option.Timeout)
+			}
+
+			break
+		}
+
+		time.Sleep(repairer.option.PollInterval)
+
+		var stillPending []*RepairItem
+
+		for _, v := range pending {
+			status, err := clients[v.Node].GetSyncStatus(*v.TxSeq)
+			if err != nil {
+				v.Error = errors.WithMessage(err, "Failed to get sync status").Error()
+				continue
+			}
+
+			v.SyncStatus = status
+
+			if !IsSyncFinished(status) {
+				stillPending = append(stillPending, v)
+			}
+		}
+
+		pending = stillPending
+
+		logrus.WithFields(logrus.Fields{
+			"pending": len(pending),
+			"total":   len(items),
+		}).Info("Wait for file sync finished")
+	}
+
+	return items
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRepairer(t *testing.T, timeout time.Duration, services ...*testNodeService) *Repairer {
+	var clients []*Client
+	for _, v := range services {
+		clients = append(clients, newTestNodeClient(t, v))
+	}
+
+	repairer, err := NewRepairer(clients, RepairOption{PollInterval: time.Millisecond, Timeout: timeout})
+	assert.NoError(t, err)
+
+	return repairer
+}
+
+func TestNewRepairer(t *testing.T) {
+	client := newTestNodeClient(t, newTestNodeService())
+
+	_, err := NewRepairer([]*Client{client, client})
+	assert.Error(t, err)
+
+	_, err = NewRepairer([]*Client{client}, RepairOption{})
+	assert.Error(t, err)
+
+	_, err = NewRepairer([]*Client{client})
+	assert.NoError(t, err)
+}
+
+func TestRepairByRoots(t *testing.T) {
+	root := common.HexToHash("0x1234")
+	tx := Transaction{DataMerkleRoot: root, Seq: 3}
+
+	finalized := newTestNodeService(&FileInfo{Tx: tx, Finalized: true})
+	unfinalized := newTestNodeService(&FileInfo{Tx: tx})
+	unfinalized.completedPolls = 2
+	missing := newTestNodeService()
+	missing.syncFailed = true
+
+	repairer := newTestRepairer(t, 0, finalized, unfinalized, missing)
+	items := repairer.RepairByRoots([]common.Hash{root, common.HexToHash("0x5678")})
+	assert.Equal(t, 6, len(items))
+
+	// file found on some storage nodes
+	for _, v := range items[:3] {
+		assert.Equal(t, uint64(3), *v.TxSeq)
+		assert.Equal(t, root, *v.Root)
+	}
+
+	assert.Equal(t, FileStateFinalized, items[0].State)
+	assert.Empty(t, items[0].SyncStatus)
+	assert.True(t, items[0].Repaired())
+	assert.False(t, finalized.started(3))
+
+	assert.Equal(t, FileStateUnfinalized, items[1].State)
+	assert.Equal(t, SyncStatusCompleted, items[1].SyncStatus)
+	assert.True(t, items[1].Repaired())
+
+	assert.Equal(t, FileStateMissing, items[2].State)
+	assert.Equal(t, "Failed(no peers found)", items[2].SyncStatus)
+	assert.False(t, items[2].Repaired())
+
+	// file not found on any storage node
+	for _, v := range items[3:] {
+		assert.Nil(t, v.TxSeq)
+		assert.Equal(t, FileStateMissing, v.State)
+		assert.NotEmpty(t, v.Error)
+		assert.False(t, v.Repaired())
+	}
+}
+
+func TestRepairByTxSeqs(t *testing.T) {
+	services := []*testNodeService{newTestNodeService(), newTestNodeService()}
+	repairer := newTestRepairer(t, 0, services...)
+
+	// file sync triggered by tx seq even not found on any storage node
+	items := repairer.RepairByTxSeqs(7, 8)
+	assert.Equal(t, 4, len(items))
+
+	for i, v := range items {
+		assert.Equal(t, uint64(7+i/2), *v.TxSeq)
+		assert.Nil(t, v.Root)
+		assert.Equal(t, FileStateMissing, v.State)
+		assert.Equal(t, SyncStatusCompleted, v.SyncStatus)
+		assert.True(t, v.Repaired())
+	}
+
+	for _, v := range services {
+		assert.True(t, v.started(7))
+		assert.True(t, v.started(8))
+	}
+}
+
+func TestRepairTimeout(t *testing.T) {
+	pending := newTestNodeService()
+	pending.completedPolls = -1
+
+	repairer := newTestRepairer(t, 100*time.Millisecond, newTestNodeService(), pending)
+
+	start := time.Now()
+	items := repairer.RepairByTxSeqs(1, 1)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.True(t, items[0].Repaired())
+	assert.Equal(t, "Downloading", items[1].SyncStatus)
+	assert.Contains(t, items[1].Error, "Timeout")
+	assert.False(t, items[1].Repaired())
+}
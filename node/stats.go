@@ -0,0 +1,75 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openweb3/go-rpc-provider"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+)
+
+// statsSmoothingFactor is the EWMA factor to track RPC latency and error rate.
+const statsSmoothingFactor = 0.2
+
+// rpcStats tracks the EWMA of latency and error rate of RPCs to a storage node.
+type rpcStats struct {
+	latency   time.Duration
+	errorRate float64
+	mu        sync.RWMutex
+}
+
+func (stats *rpcStats) get() (time.Duration, float64) {
+	stats.mu.RLock()
+	defer stats.mu.RUnlock()
+
+	return stats.latency, stats.errorRate
+}
+
+func (stats *rpcStats) record(latency time.Duration, err error) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	var failure float64
+	if err != nil {
+		failure = 1
+	} else if stats.latency == 0 {
+		stats.latency = latency
+	} else {
+		stats.latency = time.Duration(statsSmoothingFactor*float64(latency) + (1-statsSmoothingFactor)*float64(stats.latency))
+	}
+
+	stats.errorRate = statsSmoothingFactor*failure + (1-statsSmoothingFactor)*stats.errorRate
+}
+
+// recordError only updates the error rate.
+func (stats *rpcStats) recordError(err error) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	var failure float64
+	if err != nil {
+		failure = 1
+	}
+
+	stats.errorRate = statsSmoothingFactor*failure + (1-statsSmoothingFactor)*stats.errorRate
+}
+
+func (stats *rpcStats) callContextMiddleware(call providers.CallContextFunc) providers.CallContextFunc {
+	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		start := time.Now()
+		err := call(ctx, result, method, args...)
+		stats.record(time.Since(start), err)
+		return err
+	}
+}
+
+// batchCallContextMiddleware only tracks the error rate of batch RPCs, since the latency depends on
+// the batch size and is not comparable with single RPCs.
+func (stats *rpcStats) batchCallContextMiddleware(call providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+	return func(ctx context.Context, b []rpc.BatchElem) error {
+		err := call(ctx, b)
+		stats.recordError(err)
+		return err
+	}
+}
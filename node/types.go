@@ -1,6 +1,8 @@
 package node
 
 import (
+	"strings"
+
 	"github.com/Ionian-Web3-Storage/ionian-client/file/merkle"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -29,3 +31,19 @@ type SegmentWithProof struct {
 	Index uint32       `json:"index"` // segment index
 	Proof merkle.Proof `json:"proof"` // segment merkle proof
 }
+
+// Sync status of file returned by admin_getSyncStatus, e.g. Idle, FindingPeers, Downloading, Completed, Failed(reason).
+const (
+	SyncStatusCompleted = "Completed"
+	SyncStatusFailed    = "Failed"
+)
+
+// IsSyncFinished returns whether the file sync completed or failed.
+func IsSyncFinished(status string) bool {
+	return IsSyncCompleted(status) || strings.HasPrefix(status, SyncStatusFailed)
+}
+
+// IsSyncCompleted returns whether the file sync completed successfully.
+func IsSyncCompleted(status string) bool {
+	return strings.HasPrefix(status, SyncStatusCompleted)
+}